@@ -0,0 +1,151 @@
+// Command backfill reconstructs a wallet's historical BTC balance from its
+// on-chain transaction history and a historical price series, then imports
+// it into VictoriaMetrics as btc_wallet_balance_sats/_fiat samples carrying
+// the original historical timestamps -- so Grafana can chart P&L back to a
+// wallet's first transaction instead of only from whenever the live
+// exporter started scraping.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"btc-wallet-exporter/internal/backfill"
+	"btc-wallet-exporter/internal/btc"
+	"btc-wallet-exporter/internal/config"
+	"btc-wallet-exporter/internal/price"
+	"btc-wallet-exporter/internal/vminsert"
+	"btc-wallet-exporter/internal/wallet"
+)
+
+const dateLayout = "2006-01-02"
+
+func main() {
+	cfgPath := flag.String("config", "config.yml", "Path to YAML config file")
+	address := flag.String("address", "", "single BTC address to backfill (mutually exclusive with -xpub)")
+	xpub := flag.String("xpub", "", "xpub/descriptor to derive and backfill every used address of (mutually exclusive with -address)")
+	gapLimit := flag.Int("gap-limit", 20, "consecutive unused addresses before stopping derivation, when -xpub is set")
+	from := flag.String("from", "", "start of the backfill window, YYYY-MM-DD (required)")
+	to := flag.String("to", "", "end of the backfill window, YYYY-MM-DD (required)")
+	currency := flag.String("currency", "", "fiat currency to price in; defaults to price.currency in config")
+	flag.Parse()
+
+	if (*address == "") == (*xpub == "") {
+		log.Fatal("exactly one of -address or -xpub is required")
+	}
+	if *from == "" || *to == "" {
+		log.Fatal("-from and -to are required")
+	}
+	fromTime, err := time.Parse(dateLayout, *from)
+	if err != nil {
+		log.Fatalf("parse -from: %v", err)
+	}
+	toTime, err := time.Parse(dateLayout, *to)
+	if err != nil {
+		log.Fatalf("parse -to: %v", err)
+	}
+
+	cfg, err := config.Load(*cfgPath)
+	if err != nil {
+		log.Fatalf("load config: %v", err)
+	}
+
+	var btcProv btc.BalanceProvider
+	switch cfg.Bitcoin.Provider.Type {
+	case "blockstream", "":
+		btcProv = btc.NewBlockstreamProvider(cfg.Bitcoin.Provider.BaseURL, cfg.Bitcoin.Provider.UserAgent, cfg.Bitcoin.Provider.Timeout, cfg.Bitcoin.Provider.MaxRetries, cfg.Bitcoin.Provider.Backoff, cfg.Bitcoin.Provider.MaxBackoff)
+	case "bitcoind":
+		btcProv = btc.NewBitcoindProvider(
+			cfg.Bitcoin.Provider.RPCHost,
+			cfg.Bitcoin.Provider.RPCPort,
+			cfg.Bitcoin.Provider.RPCUser,
+			cfg.Bitcoin.Provider.RPCPass,
+			cfg.Bitcoin.Provider.WalletName,
+			cfg.Bitcoin.Provider.Timeout,
+		)
+	default:
+		log.Fatalf("unsupported BTC provider: %s", cfg.Bitcoin.Provider.Type)
+	}
+	hp, ok := btcProv.(btc.HistoryProvider)
+	if !ok {
+		log.Fatalf("BTC provider %s does not support transaction history", btcProv.Name())
+	}
+
+	ctx := context.Background()
+	var addrs []string
+	if *address != "" {
+		addrs = []string{*address}
+	} else {
+		checker, _ := btcProv.(wallet.ActivityChecker)
+		w, err := wallet.NewDerivedWallet("backfill", *xpub, *gapLimit, "", checker)
+		if err != nil {
+			log.Fatalf("parse xpub: %v", err)
+		}
+		addrs, err = w.ListAddresses(ctx)
+		if err != nil {
+			log.Fatalf("list addresses: %v", err)
+		}
+	}
+
+	series, err := backfill.BalanceSeries(ctx, hp, addrs)
+	if err != nil {
+		log.Fatalf("reconstruct balance series: %v", err)
+	}
+	series = backfill.InRange(series, fromTime, toTime)
+	if len(series) == 0 {
+		log.Printf("no transactions in [%s, %s], nothing to backfill", *from, *to)
+		return
+	}
+
+	if *currency == "" {
+		*currency = cfg.Price.Currency
+	}
+	priceProv, err := price.NewProviderFromConfig(cfg.Price)
+	if err != nil {
+		log.Fatalf("price provider: %v", err)
+	}
+	hpProv, ok := priceProv.(price.HistoricalPriceProvider)
+	if !ok {
+		log.Fatalf("price provider %s does not support historical ranges", priceProv.Name())
+	}
+	prices, err := hpProv.GetPriceRange(ctx, "bitcoin", *currency, fromTime, toTime)
+	if err != nil {
+		log.Fatalf("fetch historical prices: %v", err)
+	}
+	fiatSeries := backfill.WithFiat(series, prices)
+	fiatByTxID := make(map[string]float64, len(fiatSeries))
+	for _, fs := range fiatSeries {
+		fiatByTxID[fs.TxID] = fs.Fiat
+	}
+
+	label := *address
+	if label == "" {
+		label = *xpub
+	}
+	samples := make([]vminsert.Sample, 0, 2*len(series))
+	for _, s := range series {
+		samples = append(samples, vminsert.Sample{
+			Name:      "btc_wallet_balance_sats",
+			Labels:    map[string]string{"chain": "btc", "address": label},
+			Value:     float64(s.Sats),
+			Timestamp: s.Time,
+		})
+		if fiat, ok := fiatByTxID[s.TxID]; ok {
+			samples = append(samples, vminsert.Sample{
+				Name:      "btc_wallet_balance_fiat",
+				Labels:    map[string]string{"chain": "btc", "address": label, "currency": *currency},
+				Value:     fiat,
+				Timestamp: s.Time,
+			})
+		}
+	}
+
+	vm := vminsert.NewClient(cfg.Victoria.URL, cfg.Victoria.UserAgent, &http.Client{Timeout: cfg.Victoria.Timeout})
+	if err := vm.PushJSONLines(ctx, samples); err != nil {
+		log.Fatalf("push to victoriametrics: %v", err)
+	}
+	log.Printf("backfilled %d samples for %s in [%s, %s]", len(samples), label, *from, *to)
+}
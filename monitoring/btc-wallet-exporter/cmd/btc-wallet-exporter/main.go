@@ -10,9 +10,11 @@ import (
 	"time"
 
 	"btc-wallet-exporter/internal/btc"
+	"btc-wallet-exporter/internal/chain"
 	"btc-wallet-exporter/internal/config"
 	"btc-wallet-exporter/internal/exporter"
 	"btc-wallet-exporter/internal/price"
+	"btc-wallet-exporter/internal/wallet"
 )
 
 func main() {
@@ -29,7 +31,16 @@ func main() {
 	var btcProv btc.BalanceProvider
 	switch cfg.Bitcoin.Provider.Type {
 	case "blockstream", "":
-		btcProv = btc.NewBlockstreamProvider(cfg.Bitcoin.Provider.BaseURL, cfg.Bitcoin.Provider.UserAgent, cfg.Bitcoin.Provider.Timeout)
+		btcProv = btc.NewBlockstreamProvider(cfg.Bitcoin.Provider.BaseURL, cfg.Bitcoin.Provider.UserAgent, cfg.Bitcoin.Provider.Timeout, cfg.Bitcoin.Provider.MaxRetries, cfg.Bitcoin.Provider.Backoff, cfg.Bitcoin.Provider.MaxBackoff)
+	case "bitcoind":
+		btcProv = btc.NewBitcoindProvider(
+			cfg.Bitcoin.Provider.RPCHost,
+			cfg.Bitcoin.Provider.RPCPort,
+			cfg.Bitcoin.Provider.RPCUser,
+			cfg.Bitcoin.Provider.RPCPass,
+			cfg.Bitcoin.Provider.WalletName,
+			cfg.Bitcoin.Provider.Timeout,
+		)
 	default:
 		log.Fatalf("unsupported BTC provider: %s", cfg.Bitcoin.Provider.Type)
 	}
@@ -44,14 +55,53 @@ func main() {
 		priceProv = p
 	}
 
+	// xpub/descriptor wallets use the same BTC provider to tell used
+	// addresses from unused ones, if it supports that.
+	checker, _ := btcProv.(wallet.ActivityChecker)
+	wallets := make([]wallet.AddressLister, 0, len(cfg.Bitcoin.Wallets))
+	for _, wc := range cfg.Bitcoin.Wallets {
+		w, err := wallet.NewDerivedWallet(wc.Name, wc.XPub, wc.GapLimit, wc.StatePath, checker)
+		if err != nil {
+			log.Fatalf("wallet %s: %v", wc.Name, err)
+		}
+		wallets = append(wallets, w)
+	}
+
+	btcChain := chain.NewBTCAdapter(btcProv, cfg.Bitcoin.IncludeMempool)
+	targets := make([]exporter.ChainTarget, 0, len(cfg.Bitcoin.Addresses))
+	for _, addr := range cfg.Bitcoin.Addresses {
+		targets = append(targets, exporter.ChainTarget{Provider: btcChain, Address: addr})
+	}
+
+	for _, cc := range cfg.Chains {
+		switch cc.Type {
+		case "ethereum":
+			tokens := make([]chain.EthereumToken, 0, len(cc.Tokens))
+			for _, tc := range cc.Tokens {
+				tokens = append(tokens, chain.EthereumToken{
+					Symbol:   tc.Symbol,
+					Contract: tc.Contract,
+					Decimals: tc.Decimals,
+					PriceID:  tc.PriceID,
+				})
+			}
+			ethProv := chain.NewEthereumProvider(cc.RPCURL, tokens, cc.Timeout)
+			for _, addr := range cc.Addresses {
+				targets = append(targets, exporter.ChainTarget{Provider: ethProv, Address: addr})
+			}
+		default:
+			log.Fatalf("unsupported chain type: %s", cc.Type)
+		}
+	}
+
 	exp := exporter.NewExporter(
 		cfg.Server.ListenAddress,
 		cfg.Server.ReadTimeout,
 		cfg.Server.WriteTimeout,
 		cfg.Server.IdleTimeout,
-		cfg.Bitcoin.Addresses,
-		cfg.Bitcoin.IncludeMempool,
-		btcProv,
+		targets,
+		wallets,
+		btcChain,
 		priceProv,
 		cfg.Price.Currency,
 		cfg.Price.CacheTTL,
@@ -0,0 +1,95 @@
+// Package backfill reconstructs a wallet's historical satoshi/fiat balance
+// from its on-chain transaction history, so Grafana can chart P&L from the
+// wallet's first transaction instead of only from whenever the live
+// exporter started scraping.
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"btc-wallet-exporter/internal/btc"
+	"btc-wallet-exporter/internal/price"
+)
+
+// Sample is the wallet's cumulative satoshi balance immediately after one
+// confirmed transaction.
+type Sample struct {
+	Time time.Time
+	TxID string
+	Sats int64
+}
+
+// BalanceSeries merges every address's full confirmed transaction history
+// (via hp.TxHistory, which returns oldest-first) into a single chronological
+// timeline and returns the wallet's running balance after each transaction,
+// starting from zero at the wallet's first-ever transaction. addrs is
+// typically one address, or every address a DerivedWallet has ever used.
+func BalanceSeries(ctx context.Context, hp btc.HistoryProvider, addrs []string) ([]Sample, error) {
+	type delta struct {
+		t    time.Time
+		txid string
+		sats int64
+	}
+	var all []delta
+	for _, addr := range addrs {
+		txs, err := hp.TxHistory(ctx, addr)
+		if err != nil {
+			return nil, fmt.Errorf("backfill: tx history for %s: %w", addr, err)
+		}
+		for _, tx := range txs {
+			all = append(all, delta{t: tx.Time, txid: tx.TxID, sats: tx.DeltaSats})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].t.Before(all[j].t) })
+
+	samples := make([]Sample, 0, len(all))
+	var running int64
+	for _, d := range all {
+		running += d.sats
+		samples = append(samples, Sample{Time: d.t, TxID: d.txid, Sats: running})
+	}
+	return samples, nil
+}
+
+// InRange returns the subset of samples whose Time falls within [from, to].
+func InRange(samples []Sample, from, to time.Time) []Sample {
+	out := make([]Sample, 0, len(samples))
+	for _, s := range samples {
+		if s.Time.Before(from) || s.Time.After(to) {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// FiatSample pairs a balance Sample with the BTC price in effect at the
+// nearest preceding price point.
+type FiatSample struct {
+	Sample
+	Fiat float64
+}
+
+// WithFiat joins samples against a historical price series (assumed
+// sorted oldest-first, as price.HistoricalPriceProvider returns it),
+// pricing each sample at the most recent point at or before its time.
+// Samples older than the first price point are dropped, since there is no
+// price to join them against.
+func WithFiat(samples []Sample, prices []price.PricePoint) []FiatSample {
+	out := make([]FiatSample, 0, len(samples))
+	i := 0
+	for _, s := range samples {
+		for i+1 < len(prices) && !prices[i+1].Time.After(s.Time) {
+			i++
+		}
+		if len(prices) == 0 || prices[i].Time.After(s.Time) {
+			continue
+		}
+		btcAmount := float64(s.Sats) / 1e8
+		out = append(out, FiatSample{Sample: s, Fiat: btcAmount * prices[i].Value})
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package backfill
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"btc-wallet-exporter/internal/btc"
+	"btc-wallet-exporter/internal/price"
+)
+
+type fakeHistoryProvider map[string][]btc.TxDelta
+
+func (f fakeHistoryProvider) TxHistory(ctx context.Context, address string) ([]btc.TxDelta, error) {
+	return f[address], nil
+}
+
+func at(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestBalanceSeriesMergesAndAccumulates(t *testing.T) {
+	hp := fakeHistoryProvider{
+		"addr1": {
+			{TxID: "tx1", Time: at("2020-01-01T00:00:00Z"), DeltaSats: 1000},
+			{TxID: "tx3", Time: at("2020-01-03T00:00:00Z"), DeltaSats: -400},
+		},
+		"addr2": {
+			{TxID: "tx2", Time: at("2020-01-02T00:00:00Z"), DeltaSats: 500},
+		},
+	}
+
+	got, err := BalanceSeries(context.Background(), hp, []string{"addr1", "addr2"})
+	if err != nil {
+		t.Fatalf("BalanceSeries: %v", err)
+	}
+	want := []Sample{
+		{Time: at("2020-01-01T00:00:00Z"), TxID: "tx1", Sats: 1000},
+		{Time: at("2020-01-02T00:00:00Z"), TxID: "tx2", Sats: 1500},
+		{Time: at("2020-01-03T00:00:00Z"), TxID: "tx3", Sats: 1100},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("BalanceSeries() returned %d samples, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sample %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestInRangeFilters(t *testing.T) {
+	samples := []Sample{
+		{Time: at("2020-01-01T00:00:00Z"), Sats: 1000},
+		{Time: at("2020-01-02T00:00:00Z"), Sats: 1500},
+		{Time: at("2020-01-03T00:00:00Z"), Sats: 1100},
+	}
+	got := InRange(samples, at("2020-01-01T12:00:00Z"), at("2020-01-02T12:00:00Z"))
+	if len(got) != 1 || got[0].Sats != 1500 {
+		t.Fatalf("InRange() = %+v, want single 1500 sample", got)
+	}
+}
+
+func TestWithFiatJoinsNearestPrecedingPrice(t *testing.T) {
+	samples := []Sample{
+		{Time: at("2020-01-01T00:00:00Z"), Sats: 100000000}, // 1 BTC
+		{Time: at("2020-01-03T00:00:00Z"), Sats: 200000000}, // 2 BTC
+	}
+	prices := []price.PricePoint{
+		{Time: at("2019-12-31T00:00:00Z"), Value: 7000},
+		{Time: at("2020-01-02T00:00:00Z"), Value: 7500},
+	}
+
+	got := WithFiat(samples, prices)
+	if len(got) != 2 {
+		t.Fatalf("WithFiat() returned %d samples, want 2", len(got))
+	}
+	if got[0].Fiat != 7000 {
+		t.Errorf("got[0].Fiat = %v, want 7000 (priced at 2019-12-31 point)", got[0].Fiat)
+	}
+	if got[1].Fiat != 15000 {
+		t.Errorf("got[1].Fiat = %v, want 15000 (priced at 2020-01-02 point)", got[1].Fiat)
+	}
+}
+
+func TestWithFiatDropsSamplesBeforeFirstPrice(t *testing.T) {
+	samples := []Sample{{Time: at("2019-01-01T00:00:00Z"), Sats: 100000000}}
+	prices := []price.PricePoint{{Time: at("2020-01-01T00:00:00Z"), Value: 7000}}
+
+	got := WithFiat(samples, prices)
+	if len(got) != 0 {
+		t.Fatalf("WithFiat() = %+v, want empty", got)
+	}
+}
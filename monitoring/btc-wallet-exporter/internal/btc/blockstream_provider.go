@@ -4,15 +4,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"btc-wallet-exporter/internal/retry"
 )
 
 type BlockstreamProvider struct {
 	BaseURL   string
 	HTTP      *http.Client
 	UserAgent string
+
+	// Retry policy for Blockstream's HTTP API (see retry.Do).
+	// NewBlockstreamProvider fills in defaults for non-positive values; a
+	// provider built directly with zero values makes a single attempt.
+	MaxRetries int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
 }
 
 type addressResp struct {
@@ -26,40 +36,36 @@ type addressResp struct {
 	} `json:"mempool_stats"`
 }
 
-func NewBlockstreamProvider(baseURL, userAgent string, timeout time.Duration) *BlockstreamProvider {
+func NewBlockstreamProvider(baseURL, userAgent string, timeout time.Duration, maxRetries int, backoff, maxBackoff time.Duration) *BlockstreamProvider {
 	if !strings.HasPrefix(baseURL, "http") {
 		baseURL = "https://blockstream.info/api"
 	}
+	if maxRetries <= 0 {
+		maxRetries = 3
+	}
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = 5 * time.Second
+	}
 	return &BlockstreamProvider{
-		BaseURL:   baseURL,
-		HTTP:      &http.Client{Timeout: timeout},
-		UserAgent: userAgent,
+		BaseURL:    baseURL,
+		HTTP:       &http.Client{Timeout: timeout},
+		UserAgent:  userAgent,
+		MaxRetries: maxRetries,
+		Backoff:    backoff,
+		MaxBackoff: maxBackoff,
 	}
 }
 
 func (p *BlockstreamProvider) Name() string { return "blockstream" }
 
 func (p *BlockstreamProvider) GetBalance(ctx context.Context, address string, includeMempool bool) (Balance, error) {
-	url := fmt.Sprintf("%s/address/%s", strings.TrimRight(p.BaseURL, "/"), address)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return Balance{}, err
-	}
-	if p.UserAgent != "" {
-		req.Header.Set("User-Agent", p.UserAgent)
-	}
-	resp, err := p.HTTP.Do(req)
+	ar, err := p.fetchAddress(ctx, address)
 	if err != nil {
 		return Balance{}, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return Balance{}, fmt.Errorf("blockstream: status %d", resp.StatusCode)
-	}
-	var ar addressResp
-	if err := json.NewDecoder(resp.Body).Decode(&ar); err != nil {
-		return Balance{}, err
-	}
 	confirmed := ar.ChainStats.FundedTxoSum - ar.ChainStats.SpentTxoSum
 	if !includeMempool {
 		return Balance{Address: address, Sats: confirmed}, nil
@@ -67,3 +73,143 @@ func (p *BlockstreamProvider) GetBalance(ctx context.Context, address string, in
 	mempool := ar.MempoolStats.FundedTxoSum - ar.MempoolStats.SpentTxoSum
 	return Balance{Address: address, Sats: confirmed + mempool}, nil
 }
+
+// HasActivity reports whether address has ever received funds, confirmed
+// or not. It satisfies wallet.ActivityChecker so gap-limit derivation can
+// tell used addresses from unused ones.
+func (p *BlockstreamProvider) HasActivity(ctx context.Context, address string) (bool, error) {
+	ar, err := p.fetchAddress(ctx, address)
+	if err != nil {
+		return false, err
+	}
+	return ar.ChainStats.FundedTxoSum > 0 || ar.MempoolStats.FundedTxoSum > 0, nil
+}
+
+// txResp is the subset of Blockstream's /address/{addr}/txs/chain shape
+// needed to compute one transaction's net effect on an address's balance.
+type txResp struct {
+	TxID string `json:"txid"`
+	Vin  []struct {
+		Prevout struct {
+			ScriptPubkeyAddress string `json:"scriptpubkey_address"`
+			Value               int64  `json:"value"`
+		} `json:"prevout"`
+	} `json:"vin"`
+	Vout []struct {
+		ScriptPubkeyAddress string `json:"scriptpubkey_address"`
+		Value               int64  `json:"value"`
+	} `json:"vout"`
+	Status struct {
+		Confirmed bool  `json:"confirmed"`
+		BlockTime int64 `json:"block_time"`
+	} `json:"status"`
+}
+
+// TxHistory walks /address/{addr}/txs/chain[/last_seen_txid], 25
+// confirmed transactions per page newest-first, until a page comes back
+// short, then reverses the result so it reads oldest first. It satisfies
+// btc.HistoryProvider.
+func (p *BlockstreamProvider) TxHistory(ctx context.Context, address string) ([]TxDelta, error) {
+	var page []txResp
+	var all []txResp
+	lastTxID := ""
+	for {
+		var err error
+		page, err = p.fetchTxPage(ctx, address, lastTxID)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) < 25 {
+			break
+		}
+		lastTxID = page[len(page)-1].TxID
+	}
+
+	deltas := make([]TxDelta, 0, len(all))
+	for i := len(all) - 1; i >= 0; i-- {
+		tx := all[i]
+		if !tx.Status.Confirmed {
+			continue
+		}
+		var delta int64
+		for _, in := range tx.Vin {
+			if in.Prevout.ScriptPubkeyAddress == address {
+				delta -= in.Prevout.Value
+			}
+		}
+		for _, out := range tx.Vout {
+			if out.ScriptPubkeyAddress == address {
+				delta += out.Value
+			}
+		}
+		deltas = append(deltas, TxDelta{
+			TxID:      tx.TxID,
+			Time:      time.Unix(tx.Status.BlockTime, 0).UTC(),
+			DeltaSats: delta,
+		})
+	}
+	return deltas, nil
+}
+
+func (p *BlockstreamProvider) fetchTxPage(ctx context.Context, address, lastTxID string) ([]txResp, error) {
+	url := fmt.Sprintf("%s/address/%s/txs/chain", strings.TrimRight(p.BaseURL, "/"), address)
+	if lastTxID != "" {
+		url += "/" + lastTxID
+	}
+
+	var page []txResp
+	err := retry.Do(ctx, p.MaxRetries, p.Backoff, p.MaxBackoff, nil, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		if p.UserAgent != "" {
+			req.Header.Set("User-Agent", p.UserAgent)
+		}
+		resp, err := p.HTTP.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			return &retry.HTTPStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(b))}
+		}
+		page = nil
+		return json.NewDecoder(resp.Body).Decode(&page)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("blockstream: fetch txs for %s: %w", address, err)
+	}
+	return page, nil
+}
+
+func (p *BlockstreamProvider) fetchAddress(ctx context.Context, address string) (addressResp, error) {
+	url := fmt.Sprintf("%s/address/%s", strings.TrimRight(p.BaseURL, "/"), address)
+
+	var ar addressResp
+	err := retry.Do(ctx, p.MaxRetries, p.Backoff, p.MaxBackoff, nil, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		if p.UserAgent != "" {
+			req.Header.Set("User-Agent", p.UserAgent)
+		}
+		resp, err := p.HTTP.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			return &retry.HTTPStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(b))}
+		}
+		return json.NewDecoder(resp.Body).Decode(&ar)
+	})
+	if err != nil {
+		return addressResp{}, fmt.Errorf("blockstream: fetch address %s: %w", address, err)
+	}
+	return ar, nil
+}
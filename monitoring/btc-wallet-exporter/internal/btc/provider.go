@@ -1,6 +1,9 @@
 package btc
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Balance represents a wallet's balance in satoshis.
 // You could add fields for received/spent if needed later.
@@ -17,3 +20,19 @@ type BalanceProvider interface {
 	GetBalance(ctx context.Context, address string, includeMempool bool) (Balance, error)
 	Name() string
 }
+
+// TxDelta is one confirmed transaction's net effect on an address's
+// balance, used to reconstruct a running balance over time for backfill.
+type TxDelta struct {
+	TxID      string
+	Time      time.Time
+	DeltaSats int64 // positive = received, negative = spent
+}
+
+// HistoryProvider fetches an address's full confirmed transaction history,
+// oldest first. Not every BalanceProvider can support this (e.g. bitcoind's
+// stateless scantxoutset mode has no history), so it's a separate,
+// optional interface rather than part of BalanceProvider.
+type HistoryProvider interface {
+	TxHistory(ctx context.Context, address string) ([]TxDelta, error)
+}
@@ -0,0 +1,40 @@
+package chain
+
+import (
+	"context"
+	"math/big"
+
+	"btc-wallet-exporter/internal/btc"
+)
+
+// btcAdapter adapts an existing btc.BalanceProvider (block explorer or
+// full node) to the chain.BalanceProvider interface, so the exporter can
+// treat BTC addresses the same way as any other chain.
+type btcAdapter struct {
+	prov           btc.BalanceProvider
+	includeMempool bool
+}
+
+// NewBTCAdapter wraps prov so it satisfies BalanceProvider for chain "btc".
+func NewBTCAdapter(prov btc.BalanceProvider, includeMempool bool) BalanceProvider {
+	return &btcAdapter{prov: prov, includeMempool: includeMempool}
+}
+
+func (a *btcAdapter) Chain() string { return "btc" }
+func (a *btcAdapter) Name() string  { return a.prov.Name() }
+
+func (a *btcAdapter) GetBalances(ctx context.Context, address string) ([]Balance, error) {
+	bal, err := a.prov.GetBalance(ctx, address, a.includeMempool)
+	if err != nil {
+		return nil, err
+	}
+	return []Balance{{
+		Chain:    "btc",
+		Address:  address,
+		Asset:    "BTC",
+		Amount:   new(big.Int).SetUint64(bal.Sats),
+		Decimals: 8,
+		PriceID:  "bitcoin",
+		IsNative: true,
+	}}, nil
+}
@@ -0,0 +1,51 @@
+// Package chain generalizes balance lookups across blockchains: each
+// provider returns one or more Balance values (native asset plus any
+// configured tokens) for a single address, independent of the chain's
+// native unit or decimal precision.
+package chain
+
+import (
+	"context"
+	"math"
+	"math/big"
+)
+
+// Balance is one asset's balance for one address on one chain, expressed
+// in the asset's smallest unit (e.g. satoshis, wei). Divide Amount by
+// 10^Decimals to get the display-unit value (use Float).
+type Balance struct {
+	Chain    string
+	Address  string
+	Asset    string
+	Amount   *big.Int
+	Decimals int
+	// PriceID is the CoinGecko asset id used to look up a fiat price for
+	// this balance (e.g. "bitcoin", "ethereum", "tether"); empty disables
+	// fiat conversion for this asset.
+	PriceID string
+	// IsNative distinguishes a chain's native asset (BTC, ETH) from a
+	// token balance (ERC-20, ...), since they're exported under different
+	// metric names.
+	IsNative bool
+	// Contract is the token contract address; empty for native balances.
+	Contract string
+}
+
+// Float returns Amount scaled down by 10^Decimals, e.g. wei -> ETH.
+func (b Balance) Float() float64 {
+	if b.Amount == nil {
+		return 0
+	}
+	f := new(big.Float).SetInt(b.Amount)
+	f.Quo(f, big.NewFloat(math.Pow10(b.Decimals)))
+	v, _ := f.Float64()
+	return v
+}
+
+// BalanceProvider fetches every tracked balance (native asset plus
+// configured tokens) for one address on one chain.
+type BalanceProvider interface {
+	Chain() string
+	Name() string
+	GetBalances(ctx context.Context, address string) ([]Balance, error)
+}
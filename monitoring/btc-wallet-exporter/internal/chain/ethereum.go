@@ -0,0 +1,183 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EthereumToken configures one ERC-20 token tracked alongside an
+// address's native ETH balance.
+type EthereumToken struct {
+	Symbol   string
+	Contract string
+	Decimals int
+	PriceID  string // CoinGecko id, e.g. "tether"
+}
+
+// ethereumProvider fetches native ETH (eth_getBalance) and configured
+// ERC-20 balances (eth_call -> balanceOf) over a single JSON-RPC endpoint,
+// batching every call for an address into one HTTP round trip.
+type ethereumProvider struct {
+	rpcURL string
+	tokens []EthereumToken
+	client *http.Client
+}
+
+// NewEthereumProvider builds a chain.BalanceProvider backed by an
+// Ethereum-compatible JSON-RPC endpoint (geth, Erigon, Infura, Alchemy, ...).
+func NewEthereumProvider(rpcURL string, tokens []EthereumToken, timeout time.Duration) BalanceProvider {
+	return &ethereumProvider{
+		rpcURL: rpcURL,
+		tokens: tokens,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (e *ethereumProvider) Chain() string { return "ethereum" }
+func (e *ethereumProvider) Name() string  { return "ethereum-jsonrpc" }
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// GetBalances issues one JSON-RPC batch containing eth_getBalance plus one
+// eth_call per configured token, so an address with N tokens costs a
+// single HTTP round trip instead of N+1.
+func (e *ethereumProvider) GetBalances(ctx context.Context, address string) ([]Balance, error) {
+	reqs := make([]rpcRequest, 0, 1+len(e.tokens))
+	reqs = append(reqs, rpcRequest{JSONRPC: "2.0", ID: 0, Method: "eth_getBalance", Params: []any{address, "latest"}})
+	calldata, err := balanceOfCalldata(address)
+	if err != nil {
+		return nil, fmt.Errorf("ethereum: %w", err)
+	}
+	for i, t := range e.tokens {
+		reqs = append(reqs, rpcRequest{
+			JSONRPC: "2.0",
+			ID:      i + 1,
+			Method:  "eth_call",
+			Params:  []any{map[string]string{"to": t.Contract, "data": calldata}, "latest"},
+		})
+	}
+
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, fmt.Errorf("ethereum: marshal batch: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("ethereum: rpc request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("ethereum: rpc http %d", resp.StatusCode)
+	}
+
+	var rpcResps []rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResps); err != nil {
+		return nil, fmt.Errorf("ethereum: decode batch response: %w", err)
+	}
+	byID := make(map[int]rpcResponse, len(rpcResps))
+	for _, r := range rpcResps {
+		byID[r.ID] = r
+	}
+
+	nativeResp, ok := byID[0]
+	if !ok {
+		return nil, fmt.Errorf("ethereum: missing eth_getBalance response")
+	}
+	if nativeResp.Error != nil {
+		return nil, fmt.Errorf("ethereum: eth_getBalance: %s", nativeResp.Error.Message)
+	}
+	nativeWei, err := hexToBigInt(nativeResp.Result)
+	if err != nil {
+		return nil, fmt.Errorf("ethereum: parse eth_getBalance result: %w", err)
+	}
+
+	balances := make([]Balance, 0, len(reqs))
+	balances = append(balances, Balance{
+		Chain:    "ethereum",
+		Address:  address,
+		Asset:    "ETH",
+		Amount:   nativeWei,
+		Decimals: 18,
+		PriceID:  "ethereum",
+		IsNative: true,
+	})
+
+	for i, t := range e.tokens {
+		r, ok := byID[i+1]
+		if !ok {
+			return nil, fmt.Errorf("ethereum: missing balanceOf response for %s", t.Symbol)
+		}
+		if r.Error != nil {
+			return nil, fmt.Errorf("ethereum: balanceOf %s: %s", t.Symbol, r.Error.Message)
+		}
+		amt, err := hexToBigInt(r.Result)
+		if err != nil {
+			return nil, fmt.Errorf("ethereum: parse balanceOf %s result: %w", t.Symbol, err)
+		}
+		balances = append(balances, Balance{
+			Chain:    "ethereum",
+			Address:  address,
+			Asset:    t.Symbol,
+			Amount:   amt,
+			Decimals: t.Decimals,
+			PriceID:  t.PriceID,
+			Contract: t.Contract,
+		})
+	}
+	return balances, nil
+}
+
+// balanceOfCalldata builds the ABI-encoded call data for
+// balanceOf(address): the 4-byte selector 0x70a08231 followed by address
+// left-padded to 32 bytes. An Ethereum address is 20 bytes (40 hex chars),
+// so addr must fit within the 64-char word; anything longer (a malformed
+// config entry) is rejected rather than underflowing the padding count.
+func balanceOfCalldata(address string) (string, error) {
+	addr := strings.TrimPrefix(strings.ToLower(address), "0x")
+	if len(addr) > 64 {
+		return "", fmt.Errorf("address %q is longer than 32 bytes", address)
+	}
+	return "0x70a08231" + strings.Repeat("0", 64-len(addr)) + addr, nil
+}
+
+// hexToBigInt parses a JSON-RPC "0x..." quantity result.
+func hexToBigInt(raw json.RawMessage) (*big.Int, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	s = strings.TrimPrefix(s, "0x")
+	if s == "" {
+		return big.NewInt(0), nil
+	}
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid hex quantity %q", s)
+	}
+	return n, nil
+}
@@ -20,12 +20,37 @@ type BTCProvider struct {
 	BaseURL   string        `yaml:"base_url"`
 	Timeout   time.Duration `yaml:"timeout"`
 	UserAgent string        `yaml:"user_agent"`
+
+	// blockstream-only: retry policy for its HTTP API (see retry.Do).
+	MaxRetries int           `yaml:"max_retries"`
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+
+	// bitcoind-only: talks to a Bitcoin Core node over JSON-RPC instead of
+	// a block-explorer HTTP API.
+	RPCHost    string `yaml:"rpc_host"`
+	RPCPort    int    `yaml:"rpc_port"`
+	RPCUser    string `yaml:"rpc_user"`
+	RPCPass    string `yaml:"rpc_pass"`
+	WalletName string `yaml:"wallet_name"` // if set, uses importdescriptors+getbalances instead of scantxoutset
+}
+
+type XPubWallet struct {
+	Name string `yaml:"name"`
+	// XPub is either a bare xpub/ypub/zpub (and testnet tpub/upub/vpub) or
+	// an output descriptor wrapping one, e.g.
+	// "wpkh([fp/84h/0h/0h]xpub6.../0/*)#checksum". Receive (chain 0) and
+	// change (chain 1) addresses are both derived from the embedded key.
+	XPub      string `yaml:"xpub"`
+	GapLimit  int    `yaml:"gap_limit"`  // consecutive unused addresses before stopping a chain's scan; default 20
+	StatePath string `yaml:"state_path"` // persisted last-used-index cursor
 }
 
 type Bitcoin struct {
-	Addresses      []string    `yaml:"addresses"`
-	IncludeMempool bool        `yaml:"include_mempool"`
-	Provider       BTCProvider `yaml:"provider"`
+	Addresses      []string     `yaml:"addresses"`
+	Wallets        []XPubWallet `yaml:"wallets"`
+	IncludeMempool bool         `yaml:"include_mempool"`
+	Provider       BTCProvider  `yaml:"provider"`
 }
 
 type PriceProvider struct {
@@ -43,10 +68,41 @@ type Price struct {
 	Provider PriceProvider `yaml:"provider"`
 }
 
+// TokenConfig tracks one ERC-20 (or similar) token balance alongside a
+// chain's native asset.
+type TokenConfig struct {
+	Symbol   string `yaml:"symbol"`   // e.g. USDT
+	Contract string `yaml:"contract"` // token contract address
+	Decimals int    `yaml:"decimals"`
+	PriceID  string `yaml:"price_id"` // CoinGecko id, e.g. "tether"; empty disables fiat conversion
+}
+
+// ChainConfig is one non-BTC chain to watch. BTC keeps its own Bitcoin
+// block above (block-explorer/bitcoind providers, xpub wallets); Chains
+// covers everything reachable over a generic JSON-RPC endpoint.
+type ChainConfig struct {
+	Type      string        `yaml:"type"` // "ethereum"
+	RPCURL    string        `yaml:"rpc_url"`
+	Addresses []string      `yaml:"addresses"`
+	Tokens    []TokenConfig `yaml:"tokens"`
+	Timeout   time.Duration `yaml:"timeout"`
+}
+
+// VictoriaConfig is the VictoriaMetrics endpoint the backfill command
+// imports historical samples into. The live exporter only exposes
+// /metrics for Prometheus to scrape, so this is unused outside backfill.
+type VictoriaConfig struct {
+	URL       string        `yaml:"url"` // http://victoria-metrics:8428
+	Timeout   time.Duration `yaml:"timeout"`
+	UserAgent string        `yaml:"user_agent"`
+}
+
 type Config struct {
-	Server  Server  `yaml:"server"`
-	Bitcoin Bitcoin `yaml:"bitcoin"`
-	Price   Price   `yaml:"price"`
+	Server   Server         `yaml:"server"`
+	Bitcoin  Bitcoin        `yaml:"bitcoin"`
+	Chains   []ChainConfig  `yaml:"chains"`
+	Price    Price          `yaml:"price"`
+	Victoria VictoriaConfig `yaml:"victoria"`
 }
 
 func Load(path string) (*Config, error) {
@@ -80,6 +136,19 @@ func Load(path string) (*Config, error) {
 	if c.Bitcoin.Provider.BaseURL == "" {
 		c.Bitcoin.Provider.BaseURL = "https://blockstream.info/api"
 	}
+	if c.Bitcoin.Provider.Type == "bitcoind" && c.Bitcoin.Provider.RPCPort == 0 {
+		c.Bitcoin.Provider.RPCPort = 8332
+	}
+	for i := range c.Bitcoin.Wallets {
+		if c.Bitcoin.Wallets[i].GapLimit == 0 {
+			c.Bitcoin.Wallets[i].GapLimit = 20
+		}
+	}
+	for i := range c.Chains {
+		if c.Chains[i].Timeout == 0 {
+			c.Chains[i].Timeout = 10 * time.Second
+		}
+	}
 	if c.Price.Currency == "" {
 		c.Price.Currency = "EUR"
 	}
@@ -95,5 +164,8 @@ func Load(path string) (*Config, error) {
 	if c.Price.Provider.BaseURL == "" {
 		c.Price.Provider.BaseURL = "https://api.coingecko.com/api/v3"
 	}
+	if c.Victoria.Timeout == 0 {
+		c.Victoria.Timeout = 10 * time.Second
+	}
 	return &c, nil
 }
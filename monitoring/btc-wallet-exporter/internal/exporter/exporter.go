@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/big"
 	"net/http"
 	"strings"
 	"sync"
@@ -12,91 +13,101 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
-	"btc-wallet-exporter/internal/btc"
-	"btc-wallet-exporter/internal/config"
+	"btc-wallet-exporter/internal/chain"
 	"btc-wallet-exporter/internal/price"
+	"btc-wallet-exporter/internal/wallet"
 )
 
+// ChainTarget pairs a chain.BalanceProvider with one address it should
+// scrape. A single exporter instance watches as many chains/addresses as
+// are configured, each through whichever provider its chain type needs.
+type ChainTarget struct {
+	Provider chain.BalanceProvider
+	Address  string
+}
+
 type Exporter struct {
-	Addresses      []string
-	IncludeMempool bool
-	BTC            btc.BalanceProvider
-	Price          price.PriceProvider // optional (can be nil)
-	PriceCurrency  string
-	PriceTTL       time.Duration
-	priceProvider  config.PriceProvider
+	Targets       []ChainTarget
+	Wallets       []wallet.AddressLister // xpub/descriptor BTC wallets, aggregated into wallet_balance_native{chain="btc"}
+	WalletChain   chain.BalanceProvider  // provider used to fetch balances of Wallets' derived addresses
+	Price         price.PriceProvider    // optional (can be nil)
+	PriceCurrency string
+	PriceTTL      time.Duration
 
 	// internals
 	mux    *http.ServeMux
 	server *http.Server
 	// metrics
-	balanceSats   *prometheus.GaugeVec
-	balanceBTC    *prometheus.GaugeVec
+	balanceNative *prometheus.GaugeVec
+	balanceToken  *prometheus.GaugeVec
 	balanceFiat   *prometheus.GaugeVec
 	scrapeDur     prometheus.Summary
 	reqTotal      *prometheus.CounterVec
 	lastSuccessTS *prometheus.GaugeVec
 	priceGauge    *prometheus.GaugeVec
 
-	priceCacheUntil time.Time
-	priceCacheVal   float64
-	priceCacheCur   string
-	mu              sync.RWMutex
+	priceCacheMu sync.RWMutex
+	priceCache   map[string]cachedPrice // key: assetID+"|"+currency
+}
+
+type cachedPrice struct {
+	val   float64
+	until time.Time
 }
 
-func NewExporter(addr string, readTO, writeTO, idleTO time.Duration, addresses []string, includeMempool bool, btcProv btc.BalanceProvider, priceProv price.PriceProvider, priceCur string, priceTTL time.Duration) *Exporter {
+func NewExporter(addr string, readTO, writeTO, idleTO time.Duration, targets []ChainTarget, wallets []wallet.AddressLister, walletChain chain.BalanceProvider, priceProv price.PriceProvider, priceCur string, priceTTL time.Duration) *Exporter {
 
 	mux := http.NewServeMux()
 	e := &Exporter{
-		Addresses:      addresses,
-		IncludeMempool: includeMempool,
-		BTC:            btcProv,
-		Price:          priceProv,
-		PriceCurrency:  priceCur,
-		PriceTTL:       priceTTL,
-		mux:            mux,
+		Targets:       targets,
+		Wallets:       wallets,
+		WalletChain:   walletChain,
+		Price:         priceProv,
+		PriceCurrency: priceCur,
+		PriceTTL:      priceTTL,
+		mux:           mux,
+		priceCache:    make(map[string]cachedPrice),
 	}
 	// Register metrics
-	e.balanceSats = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: "btc",
-		Name:      "wallet_balance_sats",
-		Help:      "BTC wallet balance in satoshis",
-	}, []string{"address"})
-	e.balanceBTC = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: "btc",
-		Name:      "wallet_balance_btc",
-		Help:      "BTC wallet balance in BTC",
-	}, []string{"address"})
+	e.balanceNative = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "wallet",
+		Name:      "balance_native",
+		Help:      "Wallet balance in the chain's native asset's display units (e.g. BTC, ETH)",
+	}, []string{"chain", "address", "asset"})
+	e.balanceToken = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "wallet",
+		Name:      "balance_token",
+		Help:      "Wallet balance of a token asset (e.g. ERC-20) in its display units",
+	}, []string{"chain", "address", "token", "contract"})
 	e.balanceFiat = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: "btc",
-		Name:      "wallet_balance_fiat",
-		Help:      "BTC wallet balance in configured fiat currency",
-	}, []string{"address", "currency"})
+		Namespace: "wallet",
+		Name:      "balance_fiat",
+		Help:      "Wallet balance converted to the configured fiat currency",
+	}, []string{"chain", "address", "asset", "currency"})
 	e.scrapeDur = prometheus.NewSummary(prometheus.SummaryOpts{
-		Namespace: "btc_exporter",
+		Namespace: "wallet_exporter",
 		Name:      "scrape_duration_seconds",
 		Help:      "Time spent scraping balances",
 	})
 	e.reqTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Namespace: "btc_exporter",
+		Namespace: "wallet_exporter",
 		Name:      "requests_total",
 		Help:      "Number of provider requests by status",
 	}, []string{"provider", "status"})
 	e.lastSuccessTS = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: "btc_exporter",
+		Namespace: "wallet_exporter",
 		Name:      "last_success_timestamp_seconds",
 		Help:      "Unix timestamp of the last successful full scrape",
 	}, []string{"provider"})
 	e.priceGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-		Namespace: "btc",
-		Name:      "price",
-		Help:      "Price of 1 BTC in the configured fiat currency",
-	}, []string{"currency"})
-	prometheus.MustRegister(e.priceGauge)
+		Namespace: "wallet",
+		Name:      "asset_price",
+		Help:      "Price of 1 unit of an asset in the configured fiat currency",
+	}, []string{"asset", "currency"})
 
 	prometheus.MustRegister(
-		e.balanceSats, e.balanceBTC, e.balanceFiat,
-		e.scrapeDur, e.reqTotal, e.lastSuccessTS,
+		e.balanceNative, e.balanceToken, e.balanceFiat,
+		e.scrapeDur, e.reqTotal, e.lastSuccessTS, e.priceGauge,
 	)
 
 	mux.Handle("/metrics", promhttp.Handler())
@@ -122,42 +133,46 @@ func (e *Exporter) Collect(ctx context.Context) error {
 	start := time.Now()
 	defer func() { e.scrapeDur.Observe(time.Since(start).Seconds()) }()
 
-	if e.BTC == nil {
-		return errors.New("no BTC provider configured")
-	}
-
-	// Optional: get price (cached)
-	var priceVal float64
-	if e.Price != nil && e.PriceCurrency != "" {
-		priceVal = e.getPriceCached(ctx)
-	}
-	if priceVal > 0 {
-		e.priceGauge.WithLabelValues(strings.ToUpper(e.PriceCurrency)).Set(priceVal)
+	if len(e.Targets) == 0 && len(e.Wallets) == 0 {
+		return errors.New("no chain targets or wallets configured")
 	}
 
 	wg := sync.WaitGroup{}
-	wg.Add(len(e.Addresses))
-	errCh := make(chan error, len(e.Addresses))
+	wg.Add(len(e.Targets) + len(e.Wallets))
+	errCh := make(chan error, len(e.Targets)+len(e.Wallets))
 
-	for _, addr := range e.Addresses {
-		address := addr
+	for _, target := range e.Targets {
+		tg := target
 		go func() {
 			defer wg.Done()
 			c, cancel := context.WithTimeout(ctx, 10*time.Second)
 			defer cancel()
-			bal, err := e.BTC.GetBalance(c, address, e.IncludeMempool)
+			balances, err := tg.Provider.GetBalances(c, tg.Address)
 			if err != nil {
-				e.reqTotal.WithLabelValues(e.BTC.Name(), "error").Inc()
-				errCh <- fmt.Errorf("address %s: %w", address, err)
+				e.reqTotal.WithLabelValues(tg.Provider.Name(), "error").Inc()
+				errCh <- fmt.Errorf("%s %s: %w", tg.Provider.Chain(), tg.Address, err)
 				return
 			}
-			e.reqTotal.WithLabelValues(e.BTC.Name(), "ok").Inc()
-			btcVal := float64(bal.Sats) / 1e8
-			e.balanceSats.WithLabelValues(address).Set(float64(bal.Sats))
-			e.balanceBTC.WithLabelValues(address).Set(btcVal)
-			if priceVal > 0 {
-				e.balanceFiat.WithLabelValues(address, strings.ToUpper(e.PriceCurrency)).Set(btcVal * priceVal)
+			e.reqTotal.WithLabelValues(tg.Provider.Name(), "ok").Inc()
+			for _, bal := range balances {
+				e.observeBalance(ctx, bal)
+			}
+		}()
+	}
+	for _, w := range e.Wallets {
+		wlt := w
+		go func() {
+			defer wg.Done()
+			c, cancel := context.WithTimeout(ctx, 30*time.Second)
+			defer cancel()
+			total, err := e.collectWallet(c, wlt)
+			if err != nil {
+				e.reqTotal.WithLabelValues(e.WalletChain.Name(), "error").Inc()
+				errCh <- fmt.Errorf("wallet %s: %w", wlt.Name(), err)
+				return
 			}
+			total.Address = wlt.Name()
+			e.observeBalance(ctx, total)
 		}()
 	}
 	wg.Wait()
@@ -171,29 +186,82 @@ func (e *Exporter) Collect(ctx context.Context) error {
 		}
 	}
 	if gotErr == nil {
-		e.lastSuccessTS.WithLabelValues(e.BTC.Name()).Set(float64(time.Now().Unix()))
+		e.lastSuccessTS.WithLabelValues("exporter").Set(float64(time.Now().Unix()))
 	}
 	return gotErr
 }
 
-// getPriceCached reads or refreshes the cached price.
-func (e *Exporter) getPriceCached(ctx context.Context) float64 {
-	e.mu.RLock()
-	if time.Now().Before(e.priceCacheUntil) && e.priceCacheCur == e.PriceCurrency && e.priceCacheVal > 0 {
-		val := e.priceCacheVal
-		e.mu.RUnlock()
-		return val
+// observeBalance updates the native/token gauge for bal and, if bal.PriceID
+// is set and a price provider is configured, the fiat gauge too.
+func (e *Exporter) observeBalance(ctx context.Context, bal chain.Balance) {
+	v := bal.Float()
+	if bal.IsNative {
+		e.balanceNative.WithLabelValues(bal.Chain, bal.Address, bal.Asset).Set(v)
+	} else {
+		e.balanceToken.WithLabelValues(bal.Chain, bal.Address, bal.Asset, bal.Contract).Set(v)
+	}
+
+	if bal.PriceID == "" || e.Price == nil || e.PriceCurrency == "" {
+		return
+	}
+	priceVal := e.getPriceCached(ctx, bal.PriceID)
+	if priceVal <= 0 {
+		return
+	}
+	e.priceGauge.WithLabelValues(bal.Asset, strings.ToUpper(e.PriceCurrency)).Set(priceVal)
+	e.balanceFiat.WithLabelValues(bal.Chain, bal.Address, bal.Asset, strings.ToUpper(e.PriceCurrency)).Set(v * priceVal)
+}
+
+// collectWallet lists wlt's derived addresses and sums their balances into
+// a single synthetic chain.Balance, one GetBalances call per address. Per-
+// address metrics are not emitted for derived wallets to keep cardinality
+// bounded by the gap limit rather than the full derivation history.
+func (e *Exporter) collectWallet(ctx context.Context, wlt wallet.AddressLister) (chain.Balance, error) {
+	addrs, err := wlt.ListAddresses(ctx)
+	if err != nil {
+		return chain.Balance{}, fmt.Errorf("list addresses: %w", err)
+	}
+	total := chain.Balance{Chain: e.WalletChain.Chain(), IsNative: true}
+	for _, addr := range addrs {
+		balances, err := e.WalletChain.GetBalances(ctx, addr)
+		if err != nil {
+			return chain.Balance{}, fmt.Errorf("address %s: %w", addr, err)
+		}
+		for _, bal := range balances {
+			if !bal.IsNative {
+				continue
+			}
+			if total.Amount == nil {
+				total.Amount = new(big.Int)
+				total.Asset = bal.Asset
+				total.Decimals = bal.Decimals
+				total.PriceID = bal.PriceID
+			}
+			total.Amount.Add(total.Amount, bal.Amount)
+		}
+	}
+	if total.Amount == nil {
+		total.Amount = new(big.Int)
+	}
+	return total, nil
+}
+
+// getPriceCached reads or refreshes the cached price for assetID.
+func (e *Exporter) getPriceCached(ctx context.Context, assetID string) float64 {
+	key := assetID + "|" + e.PriceCurrency
+	e.priceCacheMu.RLock()
+	if c, ok := e.priceCache[key]; ok && time.Now().Before(c.until) {
+		e.priceCacheMu.RUnlock()
+		return c.val
 	}
-	e.mu.RUnlock()
+	e.priceCacheMu.RUnlock()
 
-	p, err := e.Price.GetBTCPrice(ctx, e.PriceCurrency)
+	p, err := e.Price.GetPrice(ctx, assetID, e.PriceCurrency)
 	if err != nil {
 		return 0
 	}
-	e.mu.Lock()
-	defer e.mu.Unlock()
-	e.priceCacheVal = p.Value
-	e.priceCacheCur = e.PriceCurrency
-	e.priceCacheUntil = time.Now().Add(e.PriceTTL)
+	e.priceCacheMu.Lock()
+	e.priceCache[key] = cachedPrice{val: p.Value, until: time.Now().Add(e.PriceTTL)}
+	e.priceCacheMu.Unlock()
 	return p.Value
 }
@@ -3,7 +3,6 @@ package price
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -52,13 +51,19 @@ func NewCoinGecko(baseURL, apiKey, userAgent string, timeout time.Duration) *Coi
 
 func (c *CoinGecko) Name() string { return "coingecko" }
 
-func (c *CoinGecko) GetBTCPrice(ctx context.Context, fiat string) (Price, error) {
+// GetPrice looks up assetID's price (CoinGecko's own id, e.g. "bitcoin",
+// "ethereum", "tether") in fiat.
+func (c *CoinGecko) GetPrice(ctx context.Context, assetID, fiat string) (Price, error) {
+	assetID = strings.TrimSpace(assetID)
+	if assetID == "" {
+		assetID = "bitcoin"
+	}
 	fiat = strings.ToLower(strings.TrimSpace(fiat))
 	if fiat == "" {
 		fiat = "usd"
 	}
 	q := url.Values{}
-	q.Set("ids", "bitcoin")
+	q.Set("ids", assetID)
 	q.Set("vs_currencies", fiat)
 
 	u := fmt.Sprintf("%s/simple/price?%s", c.baseURL, q.Encode())
@@ -91,9 +96,9 @@ func (c *CoinGecko) GetBTCPrice(ctx context.Context, fiat string) (Price, error)
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
 		return Price{}, err
 	}
-	m, ok := data["bitcoin"]
+	m, ok := data[assetID]
 	if !ok {
-		return Price{}, errors.New("coingecko: missing 'bitcoin' key")
+		return Price{}, fmt.Errorf("coingecko: missing '%s' key", assetID)
 	}
 	val, ok := m[fiat]
 	if !ok {
@@ -101,3 +106,67 @@ func (c *CoinGecko) GetBTCPrice(ctx context.Context, fiat string) (Price, error)
 	}
 	return Price{Value: val}, nil
 }
+
+// marketChartRangeResp is the subset of /coins/{id}/market_chart/range
+// needed for a price series: a list of [unix_millis, price] pairs.
+type marketChartRangeResp struct {
+	Prices [][2]float64 `json:"prices"`
+}
+
+// GetPriceRange looks up assetID's historical price in fiat between from
+// and to via /coins/{id}/market_chart/range, which CoinGecko buckets at an
+// interval that widens with the range (roughly 5-minutely under a day,
+// hourly under 90 days, daily beyond that) -- callers that need a specific
+// granularity should query in matching sized windows.
+func (c *CoinGecko) GetPriceRange(ctx context.Context, assetID, fiat string, from, to time.Time) ([]PricePoint, error) {
+	assetID = strings.TrimSpace(assetID)
+	if assetID == "" {
+		assetID = "bitcoin"
+	}
+	fiat = strings.ToLower(strings.TrimSpace(fiat))
+	if fiat == "" {
+		fiat = "usd"
+	}
+	q := url.Values{}
+	q.Set("vs_currency", fiat)
+	q.Set("from", fmt.Sprintf("%d", from.Unix()))
+	q.Set("to", fmt.Sprintf("%d", to.Unix()))
+
+	u := fmt.Sprintf("%s/coins/%s/market_chart/range?%s", c.baseURL, assetID, q.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("x-cg-pro-api-key", c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("coingecko: rate limited (%d)", resp.StatusCode)
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("coingecko: http %d", resp.StatusCode)
+	}
+
+	var data marketChartRangeResp
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	points := make([]PricePoint, 0, len(data.Prices))
+	for _, p := range data.Prices {
+		points = append(points, PricePoint{
+			Time:  time.UnixMilli(int64(p[0])).UTC(),
+			Value: p[1],
+		})
+	}
+	return points, nil
+}
@@ -0,0 +1,71 @@
+package price
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"btc-wallet-exporter/internal/testkit"
+)
+
+func TestCoinGeckoGetPrice(t *testing.T) {
+	rt, err := testkit.LoadFixtures("testdata/vectors/coingecko")
+	if err != nil {
+		t.Fatalf("load fixtures: %v", err)
+	}
+
+	c := NewCoinGecko("https://fixture.test/api/v3", "", "", 5*time.Second)
+	c.client.Transport = rt
+
+	got, err := c.GetPrice(context.Background(), "bitcoin", "usd")
+	if err != nil {
+		t.Fatalf("GetPrice: %v", err)
+	}
+	if want := 65000.5; got.Value != want {
+		t.Errorf("GetPrice() = %v, want %v", got.Value, want)
+	}
+}
+
+func TestCoinGeckoGetPriceRange(t *testing.T) {
+	rt, err := testkit.LoadFixtures("testdata/vectors/coingecko")
+	if err != nil {
+		t.Fatalf("load fixtures: %v", err)
+	}
+
+	c := NewCoinGecko("https://fixture.test/api/v3", "", "", 5*time.Second)
+	c.client.Transport = rt
+
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(2 * time.Hour)
+	got, err := c.GetPriceRange(context.Background(), "bitcoin", "usd", from, to)
+	if err != nil {
+		t.Fatalf("GetPriceRange: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("GetPriceRange() returned %d points, want 3", len(got))
+	}
+	if want := 7200.17; got[0].Value != want {
+		t.Errorf("GetPriceRange()[0].Value = %v, want %v", got[0].Value, want)
+	}
+	if !got[0].Time.Equal(from) {
+		t.Errorf("GetPriceRange()[0].Time = %v, want %v", got[0].Time, from)
+	}
+}
+
+func TestCoinGeckoGetPriceDefaultsFiatToUSD(t *testing.T) {
+	rt, err := testkit.LoadFixtures("testdata/vectors/coingecko")
+	if err != nil {
+		t.Fatalf("load fixtures: %v", err)
+	}
+
+	c := NewCoinGecko("https://fixture.test/api/v3", "", "", 5*time.Second)
+	c.client.Transport = rt
+
+	got, err := c.GetPrice(context.Background(), "bitcoin", "")
+	if err != nil {
+		t.Fatalf("GetPrice: %v", err)
+	}
+	if want := 65000.5; got.Value != want {
+		t.Errorf("GetPrice() = %v, want %v", got.Value, want)
+	}
+}
@@ -2,14 +2,33 @@ package price
 
 import (
 	"context"
+	"time"
 )
 
 type Price struct {
 	Currency string  // e.g., USD
-	Value    float64 // price of 1 BTC in currency
+	Value    float64 // price of 1 unit of the looked-up asset in currency
 }
 
+// PriceProvider looks up the fiat price of one unit of an asset. assetID is
+// the provider's own identifier for the asset, e.g. CoinGecko's "bitcoin",
+// "ethereum", or "tether".
 type PriceProvider interface {
-	GetBTCPrice(ctx context.Context, currency string) (Price, error)
+	GetPrice(ctx context.Context, assetID, currency string) (Price, error)
 	Name() string
 }
+
+// PricePoint is one sample of a historical price series.
+type PricePoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// HistoricalPriceProvider looks up a fiat price series over a date range,
+// the signal the backfill command needs to join against a wallet's past
+// balances. Not every PriceProvider can support this (CoinGecko's free
+// tier only grants range queries, not every provider exposes one), so
+// it's a separate, optional interface rather than part of PriceProvider.
+type HistoricalPriceProvider interface {
+	GetPriceRange(ctx context.Context, assetID, currency string, from, to time.Time) ([]PricePoint, error)
+}
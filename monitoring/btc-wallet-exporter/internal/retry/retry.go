@@ -0,0 +1,131 @@
+// Package retry provides an HTTP retry helper with decorrelated-jitter
+// backoff, for providers (e.g. btc.BlockstreamProvider) that need to ride
+// out transient upstream failures without hammering it on a fixed schedule.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPStatusError wraps a non-2xx HTTP response so Do's classifier can tell
+// a permanent client error from one worth retrying. Callers build one from
+// the response they just read instead of a bare fmt.Errorf, e.g.:
+//
+//	return &retry.HTTPStatusError{StatusCode: resp.StatusCode, Body: string(body)}
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("http %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the response is worth retrying: 5xx, 408
+// (timeout), and 429 (rate limit) are; other 4xx (bad auth, bad request,
+// not found, ...) are permanent -- retrying won't change the outcome.
+func (e *HTTPStatusError) Retryable() bool {
+	if e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return e.StatusCode/100 != 4
+}
+
+// retryable is satisfied by errors (such as *HTTPStatusError) that know
+// whether they're worth retrying. Errors that don't implement it -- plain
+// network errors, timeouts, EOF -- are always treated as retryable, since
+// those are exactly the transient failures Do exists to ride out.
+type retryable interface{ Retryable() bool }
+
+func isRetryable(err error) bool {
+	var r retryable
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+	return true
+}
+
+// jitterSource is satisfied by *rand.Rand; callers that want deterministic
+// backoff (tests) pass one in. See defaultJitter for the nil case.
+type jitterSource interface{ Int63n(n int64) int64 }
+
+// defaultJitter backs Do calls that pass a nil rnd. It's shared and
+// mutex-guarded rather than each call seeding its own *rand.Rand from
+// time.Now().UnixNano(): callers that start retrying at the same instant
+// (e.g. a backfill run hitting a downed upstream on every address at once)
+// would otherwise be liable to land on the same or nearby seeds, producing
+// exactly the synchronized retries decorrelated jitter is meant to avoid.
+type lockedRand struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func (l *lockedRand) Int63n(n int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rnd.Int63n(n)
+}
+
+var defaultJitter = &lockedRand{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// nextDelay computes the next AWS-style "decorrelated jitter" backoff:
+// sleep = min(max, random_between(initial, prev*3)). Unlike plain doubling,
+// this avoids synchronized retries across callers hitting the same upstream
+// at once, since each caller's next sleep is drawn from a growing range
+// rather than a fixed multiple of the last one.
+func nextDelay(rnd jitterSource, prev, initial, max time.Duration) time.Duration {
+	hi := prev * 3
+	if hi < initial {
+		hi = initial
+	}
+	d := initial + time.Duration(rnd.Int63n(int64(hi-initial)+1))
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// Do calls fn up to attempts times, sleeping between attempts with
+// decorrelated jitter bounded by [initial, max] (see nextDelay). rnd seeds
+// the jitter so callers -- and their tests -- can make backoff
+// deterministic; pass nil to use the shared defaultJitter source.
+//
+// If fn's error satisfies retryable (e.g. *HTTPStatusError), a non-retryable
+// error returns immediately instead of burning through the remaining
+// attempts.
+func Do(ctx context.Context, attempts int, initial, max time.Duration, rnd jitterSource, fn func() error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if rnd == nil {
+		rnd = defaultJitter
+	}
+
+	d := initial
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			d = nextDelay(rnd, d, initial, max)
+			select {
+			case <-time.After(d):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		if !isRetryable(err) {
+			return err
+		}
+	}
+	return err
+}
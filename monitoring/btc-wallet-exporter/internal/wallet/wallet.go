@@ -0,0 +1,300 @@
+// Package wallet derives receive/change addresses from a BIP32 xpub (or an
+// output descriptor wrapping one) and tracks how far the derivation
+// frontier has advanced, so the exporter can watch a whole wallet from a
+// single public key instead of a hand-maintained address list.
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/base58"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// AddressLister yields the set of addresses a Collect cycle should scrape
+// for a logical wallet. Implementations may return a fixed list (plain
+// config.Bitcoin.Addresses) or, as here, a derived and gap-limited set.
+type AddressLister interface {
+	Name() string
+	ListAddresses(ctx context.Context) ([]string, error)
+}
+
+// ActivityChecker reports whether an address has ever been used on-chain,
+// the signal gap-limit derivation needs to decide when to stop scanning a
+// chain. BalanceProvider implementations may optionally implement this.
+type ActivityChecker interface {
+	HasActivity(ctx context.Context, address string) (bool, error)
+}
+
+// addressType is the script type encoded by an xpub/ypub/zpub prefix (or a
+// descriptor's wrapping function), used to pick how a derived pubkey is
+// turned into an address.
+type addressType int
+
+const (
+	addressTypeP2PKH addressType = iota
+	addressTypeP2SHP2WPKH
+	addressTypeP2WPKH
+)
+
+var descriptorKeyRE = regexp.MustCompile(`([tuvxyz]pub[A-Za-z0-9]+)`)
+
+// Cursor is the persisted frontier: the highest index on each chain known
+// to have been used, so restarts don't re-scan from zero every time.
+type Cursor struct {
+	LastUsedReceive int `json:"last_used_receive"` // chain 0, -1 if none used yet
+	LastUsedChange  int `json:"last_used_change"`  // chain 1
+}
+
+// DerivedWallet is an AddressLister backed by a single extended public key.
+type DerivedWallet struct {
+	name      string
+	key       *hdkeychain.ExtendedKey
+	addrType  addressType
+	params    *chaincfg.Params
+	gapLimit  int
+	statePath string
+	checker   ActivityChecker
+}
+
+// NewDerivedWallet parses xpubOrDescriptor and returns a wallet that
+// derives addresses against checker's activity signal. checker may be nil,
+// in which case ListAddresses returns, per chain, every address up to the
+// persisted cursor plus exactly gapLimit more (no frontier advancement).
+func NewDerivedWallet(name, xpubOrDescriptor string, gapLimit int, statePath string, checker ActivityChecker) (*DerivedWallet, error) {
+	if gapLimit <= 0 {
+		gapLimit = 20
+	}
+	keyStr := xpubOrDescriptor
+	addrType := addressTypeP2PKH
+	if strings.Contains(xpubOrDescriptor, "(") {
+		m := descriptorKeyRE.FindString(xpubOrDescriptor)
+		if m == "" {
+			return nil, fmt.Errorf("wallet %s: no extended key found in descriptor", name)
+		}
+		keyStr = m
+		switch {
+		case strings.HasPrefix(xpubOrDescriptor, "sh(wpkh("):
+			addrType = addressTypeP2SHP2WPKH
+		case strings.HasPrefix(xpubOrDescriptor, "wpkh("):
+			addrType = addressTypeP2WPKH
+		case strings.HasPrefix(xpubOrDescriptor, "pkh("):
+			addrType = addressTypeP2PKH
+		default:
+			return nil, fmt.Errorf("wallet %s: unsupported descriptor function in %q", name, xpubOrDescriptor)
+		}
+	} else {
+		switch keyStr[0] {
+		case 'y', 'u':
+			addrType = addressTypeP2SHP2WPKH
+		case 'z', 'v':
+			addrType = addressTypeP2WPKH
+		}
+	}
+
+	params := &chaincfg.MainNetParams
+	if strings.HasPrefix(keyStr, "t") || strings.HasPrefix(keyStr, "u") || strings.HasPrefix(keyStr, "v") {
+		params = &chaincfg.TestNet3Params
+	}
+
+	// hdkeychain only recognizes the HD version bytes chaincfg registers for
+	// xpub/tpub. ypub/zpub/upub/vpub carry SLIP-132 version bytes that say
+	// the same thing about script type that addrType already captures above,
+	// so rewrite them to the plain xpub/tpub version before parsing.
+	if keyStr[0] != 'x' && keyStr[0] != 't' {
+		rewritten, err := rewriteHDVersion(keyStr, params.HDPublicKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("wallet %s: %w", name, err)
+		}
+		keyStr = rewritten
+	}
+
+	key, err := hdkeychain.NewKeyFromString(keyStr)
+	if err != nil {
+		return nil, fmt.Errorf("wallet %s: parse extended key: %w", name, err)
+	}
+	if key.IsPrivate() {
+		return nil, fmt.Errorf("wallet %s: refusing to accept a private extended key", name)
+	}
+
+	return &DerivedWallet{
+		name:      name,
+		key:       key,
+		addrType:  addrType,
+		params:    params,
+		gapLimit:  gapLimit,
+		statePath: statePath,
+		checker:   checker,
+	}, nil
+}
+
+func (w *DerivedWallet) Name() string { return w.name }
+
+// ListAddresses derives addresses on both the receive (chain 0) and change
+// (chain 1) branches, advancing the frontier past the persisted cursor
+// while the activity checker keeps finding used addresses, then including
+// gapLimit more unused addresses past the last used one on each chain.
+func (w *DerivedWallet) ListAddresses(ctx context.Context) ([]string, error) {
+	cursor := w.loadCursor()
+
+	receive, lastReceive, err := w.scanChain(ctx, 0, cursor.LastUsedReceive)
+	if err != nil {
+		return nil, fmt.Errorf("wallet %s: scan receive chain: %w", w.name, err)
+	}
+	change, lastChange, err := w.scanChain(ctx, 1, cursor.LastUsedChange)
+	if err != nil {
+		return nil, fmt.Errorf("wallet %s: scan change chain: %w", w.name, err)
+	}
+
+	if lastReceive != cursor.LastUsedReceive || lastChange != cursor.LastUsedChange {
+		w.saveCursor(Cursor{LastUsedReceive: lastReceive, LastUsedChange: lastChange})
+	}
+
+	return append(receive, change...), nil
+}
+
+// scanChain derives addresses on the given chain (0=receive, 1=change)
+// from index 0. Indices up to lastUsed count as used without a lookup;
+// from lastUsed+1 on it asks the activity checker, and keeps going until
+// gapLimit consecutive addresses show no activity. It returns every address
+// derived (used and the unused gap that follows) plus the new last-used
+// index.
+func (w *DerivedWallet) scanChain(ctx context.Context, chain uint32, lastUsed int) ([]string, int, error) {
+	chainKey, err := w.key.Derive(chain)
+	if err != nil {
+		return nil, lastUsed, err
+	}
+
+	var addrs []string
+	consecutiveUnused := 0
+	idx := 0
+	for {
+		childKey, err := chainKey.Derive(uint32(idx))
+		if err != nil {
+			return nil, lastUsed, err
+		}
+		addr, err := deriveAddress(childKey, w.addrType, w.params)
+		if err != nil {
+			return nil, lastUsed, err
+		}
+		addrs = append(addrs, addr)
+
+		used := idx <= lastUsed
+		if !used && w.checker != nil {
+			has, err := w.checker.HasActivity(ctx, addr)
+			if err != nil {
+				return nil, lastUsed, err
+			}
+			used = has
+		}
+		if used {
+			lastUsed = idx
+			consecutiveUnused = 0
+		} else {
+			consecutiveUnused++
+			if consecutiveUnused >= w.gapLimit {
+				break
+			}
+		}
+		idx++
+	}
+	return addrs, lastUsed, nil
+}
+
+// rewriteHDVersion re-encodes a base58check extended key with version set
+// to targetVersion, leaving the rest of the payload (depth, parent
+// fingerprint, child number, chain code, key) untouched. This is how
+// SLIP-132 ypub/zpub/upub/vpub keys are turned into the xpub/tpub form
+// hdkeychain.NewKeyFromString understands, since the version bytes are the
+// only thing that differs.
+func rewriteHDVersion(keyStr string, targetVersion [4]byte) (string, error) {
+	decoded := base58.Decode(keyStr)
+	if len(decoded) != 82 {
+		return "", fmt.Errorf("invalid extended key %q: unexpected decoded length %d", keyStr, len(decoded))
+	}
+	payload, checksum := decoded[:78], decoded[78:]
+	if want := chainhash.DoubleHashB(payload)[:4]; !bytes.Equal(checksum, want) {
+		return "", fmt.Errorf("invalid extended key %q: bad checksum", keyStr)
+	}
+
+	rewritten := make([]byte, 78)
+	copy(rewritten, payload)
+	copy(rewritten[0:4], targetVersion[:])
+	checksum = chainhash.DoubleHashB(rewritten)[:4]
+	return base58.Encode(append(rewritten, checksum...)), nil
+}
+
+func deriveAddress(key *hdkeychain.ExtendedKey, addrType addressType, params *chaincfg.Params) (string, error) {
+	switch addrType {
+	case addressTypeP2PKH:
+		addr, err := key.Address(params)
+		if err != nil {
+			return "", err
+		}
+		return addr.EncodeAddress(), nil
+	case addressTypeP2WPKH:
+		pubKey, err := key.ECPubKey()
+		if err != nil {
+			return "", err
+		}
+		addr, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), params)
+		if err != nil {
+			return "", err
+		}
+		return addr.EncodeAddress(), nil
+	case addressTypeP2SHP2WPKH:
+		pubKey, err := key.ECPubKey()
+		if err != nil {
+			return "", err
+		}
+		witnessAddr, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pubKey.SerializeCompressed()), params)
+		if err != nil {
+			return "", err
+		}
+		witnessProgram, err := txscript.PayToAddrScript(witnessAddr)
+		if err != nil {
+			return "", err
+		}
+		addr, err := btcutil.NewAddressScriptHash(witnessProgram, params)
+		if err != nil {
+			return "", err
+		}
+		return addr.EncodeAddress(), nil
+	default:
+		return "", fmt.Errorf("unsupported address type %d", addrType)
+	}
+}
+
+func (w *DerivedWallet) loadCursor() Cursor {
+	cursor := Cursor{LastUsedReceive: -1, LastUsedChange: -1}
+	if w.statePath == "" {
+		return cursor
+	}
+	b, err := os.ReadFile(w.statePath)
+	if err != nil {
+		return cursor
+	}
+	_ = json.Unmarshal(b, &cursor)
+	return cursor
+}
+
+func (w *DerivedWallet) saveCursor(cursor Cursor) {
+	if w.statePath == "" {
+		return
+	}
+	b, err := json.Marshal(cursor)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(w.statePath, b, 0644)
+}
@@ -0,0 +1,97 @@
+package wallet
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// SLIP-132 mainnet version bytes for the script types NewDerivedWallet
+// detects by prefix. Used here to build ypub/zpub fixtures out of a plain
+// xpub, the same rewrite NewDerivedWallet itself performs in reverse.
+var (
+	ypubVersion = [4]byte{0x04, 0x9d, 0x7c, 0xb2}
+	zpubVersion = [4]byte{0x04, 0xb2, 0x47, 0x46}
+)
+
+func TestNewDerivedWalletAcceptsYpubAndZpub(t *testing.T) {
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	if err != nil {
+		t.Fatalf("decode seed: %v", err)
+	}
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	xpub, err := master.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: %v", err)
+	}
+
+	ypub, err := rewriteHDVersion(xpub.String(), ypubVersion)
+	if err != nil {
+		t.Fatalf("build ypub fixture: %v", err)
+	}
+	zpub, err := rewriteHDVersion(xpub.String(), zpubVersion)
+	if err != nil {
+		t.Fatalf("build zpub fixture: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		key        string
+		wantPrefix string
+	}{
+		{"ypub derives P2SH-P2WPKH addresses", ypub, "3"},
+		{"zpub derives P2WPKH addresses", zpub, "bc1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewDerivedWallet("test", tt.key, 1, "", nil)
+			if err != nil {
+				t.Fatalf("NewDerivedWallet(%q): %v", tt.key, err)
+			}
+			addrs, err := w.ListAddresses(context.Background())
+			if err != nil {
+				t.Fatalf("ListAddresses: %v", err)
+			}
+			if len(addrs) == 0 {
+				t.Fatalf("ListAddresses returned no addresses")
+			}
+			for _, a := range addrs {
+				if !strings.HasPrefix(a, tt.wantPrefix) {
+					t.Errorf("address %q does not have prefix %q", a, tt.wantPrefix)
+				}
+			}
+		})
+	}
+}
+
+func TestRewriteHDVersionRejectsBadChecksum(t *testing.T) {
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	if err != nil {
+		t.Fatalf("decode seed: %v", err)
+	}
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	xpub, err := master.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: %v", err)
+	}
+
+	s := xpub.String()
+	last, secondLast := len(s)-1, len(s)-2
+	corrupt := s[:secondLast] + string(s[last]) + string(s[secondLast])
+	if corrupt == s {
+		t.Skip("fixture's last two characters are identical, transposition is a no-op")
+	}
+	if _, err := rewriteHDVersion(corrupt, ypubVersion); err == nil {
+		t.Fatalf("rewriteHDVersion(%q) = nil error, want checksum failure", corrupt)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
 	"os/signal"
 	"strings"
 	"sync"
@@ -25,26 +27,45 @@ var Version = "dev"
 
 func main() {
 	var (
-		cfgPath  = flag.String("config", "/config.yml", "path to YAML config")
-		interval = flag.Duration("interval", 15*time.Minute, "run interval")
-		once     = flag.Bool("once", false, "run a single cycle then exit")
-		verbose  = flag.Bool("verbose", true, "enable verbose logging")
+		cfgPath     = flag.String("config", "/config.yml", "path to YAML config")
+		interval    = flag.Duration("interval", 15*time.Minute, "default run interval for sources with no schedule configured")
+		once        = flag.Bool("once", false, "run a single cycle per source then exit")
+		verbose     = flag.Bool("verbose", true, "enable verbose logging")
+		printSchema = flag.Bool("print-schema", false, "print the config JSON Schema to stdout and exit")
 	)
 	flag.Parse()
 
+	if *printSchema {
+		if err := config.WriteSchema(os.Stdout); err != nil {
+			log.Fatalf("generate config schema: %v", err)
+		}
+		fmt.Println()
+		return
+	}
+
 	log.Printf("multi-ingester %s starting...", Version)
 
 	cfg, err := config.Load(*cfgPath)
 	if err != nil {
 		log.Fatalf("load config: %v", err)
 	}
+	// Dedup store (memory/bolt/badger+bloom per cfg.Dedup.Backend)
+	var d store.Dedup
+	if cfg.Dedup.Enable {
+		d, err = store.NewDedupFromConfig(cfg.Dedup)
+		if err != nil {
+			log.Fatalf("init dedup: %v", err)
+		}
+		defer d.Close()
+		log.Printf("dedup enabled: backend=%s ttl=%s", cfg.Dedup.Backend, cfg.Dedup.TTL)
+	} else {
+		log.Printf("dedup disabled")
+	}
+
 	// Build sinks
 	var sinks []sink.Sink
 	if strings.TrimSpace(cfg.Loki.URL) != "" {
-		s := sink.NewLoki(cfg.Loki)
-		if err != nil {
-			log.Fatalf("init loki sink: %v", err)
-		}
+		s := sink.NewLoki(cfg.Loki, d)
 		sinks = append(sinks, s)
 	}
 	if strings.TrimSpace(cfg.Victoria.URL) != "" {
@@ -54,140 +75,180 @@ func main() {
 		}
 		sinks = append(sinks, s)
 	}
-	if len(sinks) == 0 {
-		log.Fatal("no sinks configured (need loki and/or victoria)")
+	if len(cfg.Kafka.Brokers) > 0 {
+		s, err := sink.NewKafka(cfg.Kafka)
+		if err != nil {
+			log.Fatalf("init kafka sink: %v", err)
+		}
+		sinks = append(sinks, s)
 	}
-
-	// Dedup store (in-memory)
-	var d *store.Dedup
-	if cfg.Dedup.Enable {
-		d = store.NewDedup(cfg.Dedup.MaxKeys, cfg.Dedup.TTL)
-		log.Printf("dedup enabled: max=%d ttl=%s", cfg.Dedup.MaxKeys, cfg.Dedup.TTL)
-	} else {
-		log.Printf("dedup disabled")
+	if len(sinks) == 0 {
+		log.Fatal("no sinks configured (need loki, victoria, and/or kafka)")
 	}
 
-	// Build sources
+	// Build sources, one scheduler config per source (defaulting to -interval
+	// when a source has no explicit `schedule:` block).
 	srcs := make([]source.Source, 0, len(cfg.Sources))
+	schedules := make([]config.ScheduleConfig, 0, len(cfg.Sources))
 	for _, sc := range cfg.Sources {
-		s, err := source.NewFromConfig(sc)
+		s, err := source.NewFromConfig(sc, d)
 		if err != nil {
 			log.Fatalf("build source %q: %v", sc.Type, err)
 		}
 		srcs = append(srcs, s)
 		log.Printf("configured source: %s", s.Name())
+
+		sched := sc.Schedule
+		if sched.Interval <= 0 && sched.Cron == "" {
+			sched.Interval = *interval
+		}
+		schedules = append(schedules, sched)
 	}
 	if len(srcs) == 0 {
 		log.Fatal("no sources configured")
 	}
-	// Main loop
-	// Context with signal cancel
+
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	runOnce := func() {
-		start := time.Now()
-		total := 0
+	sched := source.NewScheduler(srcs, schedules, 64)
 
-		for _, src := range srcs {
-			// Fetch
-			evs, err := src.Fetch(ctx)
-			log.Printf("DEBUG MAIN: fetched %d events from %s", len(evs), src.Name())
-			if err != nil {
-				log.Printf("fetch %s: %v", src.Name(), err)
-				continue
+	if addr := cfg.Health.ListenAddress; addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/", sched.HealthHandler())
+		served := "/healthz and /readyz"
+		if cfg.Metrics.Enable {
+			mux.Handle("/metrics", metrics.Handler())
+			served += " and /metrics"
+		}
+		go func() {
+			log.Printf("serving %s on %s", served, addr)
+			if err := http.ListenAndServe(addr, mux); err != nil {
+				log.Printf("health server: %v", err)
 			}
+		}()
+	}
 
-			// Apply dedup (if enabled)
-			if d != nil {
-				before := len(evs)
-				out := make([]model.Event, 0, len(evs))
-				for _, e := range evs {
-					key := e.Source + "::" + e.ID
-					if d.Seen(key) {
-						continue
-					}
-					out = append(out, e)
-					// Mark temp to avoid duplicates within this batch; we'll extend TTL after successful sink push
-					d.Mark(key)
-				}
-				if *verbose {
-					log.Printf("%s: dedup filtered %d -> %d", src.Name(), before, len(out))
-				}
-				evs = out
+	if addr := cfg.Metrics.Listen; cfg.Metrics.Enable && addr != "" {
+		go func() {
+			log.Printf("serving /metrics on %s", addr)
+			if err := metrics.Serve(addr); err != nil {
+				log.Printf("metrics server: %v", err)
 			}
+		}()
+	}
 
-			if len(evs) == 0 {
-				log.Printf("%s: no new events", src.Name())
-				continue
-			}
+	postEngine, err := postprocess.New(cfg.Post)
+	if err != nil {
+		log.Fatalf("build postprocess engine: %v", err)
+	}
 
-			// Post-process labels
-			if *verbose {
-				log.Printf("%s: applying postprocess rules", src.Name())
-			}
-			evs = postprocess.Apply(evs, cfg.Post)
-
-			// Fan-out to all sinks
-			var wg sync.WaitGroup
-			errCh := make(chan error, len(sinks))
-			for _, sk := range sinks {
-				sk := sk
-				wg.Add(1)
-				go func() {
-					defer wg.Done()
-					if err := sk.Push(ctx, evs); err != nil {
-						errCh <- fmt.Errorf("push %s -> %s: %w", src.Name(), sk.Name(), err)
-					}
-				}()
-			}
-			wg.Wait()
-			close(errCh)
-			hadErr := false
-			for e := range errCh {
-				hadErr = true
-				log.Println(e)
-			}
-			if hadErr {
-				// On sink error, we don't advance counters/state. Next cycle will retry.
-				continue
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sched.Run(ctx)
+	}()
+
+	// -once exits after every configured source has produced one batch
+	// (success or error), not after the first batch on the shared Out()
+	// channel -- otherwise whichever source happened to run first would
+	// cancel the ctx every other source's goroutine also depends on.
+	var onceRemaining map[string]struct{}
+	if *once {
+		onceRemaining = make(map[string]struct{}, len(srcs))
+		for _, s := range srcs {
+			onceRemaining[s.Name()] = struct{}{}
+		}
+	}
+
+	total := 0
+	for batch := range sched.Out() {
+		delete(onceRemaining, batch.Source)
+
+		if batch.Err != nil {
+			log.Printf("fetch %s: %v", batch.Source, batch.Err)
+		} else {
+			log.Printf("DEBUG MAIN: fetched %d events from %s", len(batch.Events), batch.Source)
+			total += processBatch(ctx, postEngine, sinks, d, batch.Source, batch.Events, *verbose)
+
+			if cfg.Metrics.Enable {
+				if snap := metrics.Dump(); snap != "" {
+					fmt.Println("METRICS SNAPSHOT:" + snap)
+				}
 			}
+		}
 
-			// Success path: count + update metrics
-			total += len(evs)
-			metrics.IncCounter("events_pushed_total", map[string]string{"source": src.Name()}, float64(len(evs)))
-			log.Printf("%s: pushed %d events to %d sink(s)", src.Name(), len(evs), len(sinks))
+		if *once && len(onceRemaining) == 0 {
+			cancel()
 		}
+	}
+	wg.Wait()
+	log.Printf("stopped, total events pushed=%d", total)
+}
 
-		// Optional metrics snapshot
-		if cfg.Metrics.Enable {
-			snap := metrics.Dump()
-			if snap != "" {
-				fmt.Println("METRICS SNAPSHOT:" + snap)
+// processBatch runs dedup, postprocess, and the sink fan-out for one
+// source's fetched events, returning how many were successfully pushed.
+func processBatch(ctx context.Context, postEngine *postprocess.Engine, sinks []sink.Sink, d store.Dedup, src string, evs []model.Event, verbose bool) int {
+	if d != nil {
+		before := len(evs)
+		out := make([]model.Event, 0, len(evs))
+		for _, e := range evs {
+			key := e.Source + "::" + e.ID
+			if d.Seen(key) {
+				continue
 			}
+			out = append(out, e)
+			// Mark temp to avoid duplicates within this batch; we'll extend TTL after successful sink push
+			d.Mark(key)
 		}
-
-		if *verbose {
-			log.Printf("cycle finished in %s, total events=%d", time.Since(start).Truncate(time.Millisecond), total)
+		if verbose {
+			log.Printf("%s: dedup filtered %d -> %d", src, before, len(out))
 		}
+		evs = out
 	}
 
-	// Run mode
-	log.Printf("multi-ingester started: %d source(s), interval=%s", len(srcs), interval.String())
-	runOnce()
-	if *once {
-		return
+	if len(evs) == 0 {
+		log.Printf("%s: no new events", src)
+		return 0
 	}
 
-	ticker := time.NewTicker(*interval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			log.Printf("stopping: %v", ctx.Err())
-			return
-		case <-ticker.C:
-			runOnce()
-		}
+	if verbose {
+		log.Printf("%s: applying postprocess rules", src)
+	}
+	evs = postEngine.Apply(evs)
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, len(sinks))
+	for _, sk := range sinks {
+		sk := sk
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := sk.Push(ctx, evs)
+			metrics.SetGauge("ingester_sink_push_duration_seconds", map[string]string{"sink": sk.Name()}, time.Since(start).Seconds())
+			if err != nil {
+				metrics.IncCounter("ingester_sink_push_total", map[string]string{"sink": sk.Name(), "status": "error"}, 1)
+				errCh <- fmt.Errorf("push %s -> %s: %w", src, sk.Name(), err)
+				return
+			}
+			metrics.IncCounter("ingester_sink_push_total", map[string]string{"sink": sk.Name(), "status": "ok"}, 1)
+		}()
 	}
+	wg.Wait()
+	close(errCh)
+	hadErr := false
+	for e := range errCh {
+		hadErr = true
+		log.Println(e)
+	}
+	if hadErr {
+		// On sink error, we don't advance counters/state. Next cycle will retry.
+		return 0
+	}
+
+	metrics.IncCounter("events_pushed_total", map[string]string{"source": src}, float64(len(evs)))
+	log.Printf("%s: pushed %d events to %d sink(s)", src, len(evs), len(sinks))
+	return len(evs)
 }
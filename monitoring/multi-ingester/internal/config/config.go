@@ -2,7 +2,10 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -14,12 +17,80 @@ type LokiConfig struct {
 	Job       string        `yaml:"job"`       // label value, default: multi-ingester
 	Timeout   time.Duration `yaml:"timeout"`   // request timeout
 	UserAgent string        `yaml:"user_agent"`
+
+	// Encoding selects the push transport: "json" (default), "json-gzip", or
+	// "protobuf-snappy" (logproto.PushRequest, snappy-framed).
+	Encoding string `yaml:"encoding" jsonschema:"enum=,enum=json,enum=json-gzip,enum=protobuf-snappy"`
+
+	// MaxBatchBytes/MaxBatchEntries bound a single HTTP push; once either is
+	// exceeded the sink flushes and starts a new request.
+	MaxBatchBytes   int `yaml:"max_batch_bytes"`
+	MaxBatchEntries int `yaml:"max_batch_entries"`
+
+	MaxRetries int           `yaml:"max_retries"`
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+type SchemaRegistryConfig struct {
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Subject is the schema-registry subject name; defaults to "<topic>-value".
+	Subject string `yaml:"subject"`
+}
+
+type KafkaConfig struct {
+	Brokers []string `yaml:"brokers"`
+
+	// TopicTemplate is rendered per event, e.g. "events.{source}".
+	TopicTemplate string `yaml:"topic_template"`
+
+	// Codec selects the message value encoding: "json" (default), "json-schema", or "avro".
+	Codec          string               `yaml:"codec" jsonschema:"enum=,enum=json,enum=json-schema,enum=avro"`
+	SchemaRegistry SchemaRegistryConfig `yaml:"schema_registry"`
+	AvroSchema     string               `yaml:"avro_schema"` // inline Avro schema, required when codec == "avro"
+
+	LingerMs      int `yaml:"linger_ms"`
+	MaxBatchBytes int `yaml:"max_batch_bytes"`
+
+	Idempotent bool          `yaml:"idempotent"`
+	Timeout    time.Duration `yaml:"timeout"`
 }
 
 type VictoriaConfig struct {
 	URL       string        `yaml:"url"`     // http://victoria-metrics:8428
 	Timeout   time.Duration `yaml:"timeout"` // request timeout
 	UserAgent string        `yaml:"user_agent"`
+
+	// Format selects the push transport: "text" (default, POSTs newline
+	// Prometheus exposition lines to /api/v1/import/prometheus) or
+	// "remote_write" (prompb.WriteRequest, snappy-compressed, POSTed to
+	// /api/v1/write).
+	Format string `yaml:"format" jsonschema:"enum=,enum=text,enum=remote_write"`
+
+	// MaxBatchBytes/MaxBatchEntries bound a single push; once either is
+	// exceeded the sink flushes and starts a new request.
+	MaxBatchBytes   int `yaml:"max_batch_bytes"`
+	MaxBatchEntries int `yaml:"max_batch_entries"`
+
+	// WALDir enables asynchronous batching: Push persists each chunk here
+	// and returns immediately, and a background flusher goroutine drains
+	// the directory into the configured transport every FlushInterval, so
+	// events survive a crash or restart between being accepted and being
+	// delivered. Empty (default) disables the WAL: Push sends synchronously,
+	// one request per MaxBatchBytes/MaxBatchEntries chunk.
+	WALDir string `yaml:"wal_dir"`
+	// FlushInterval is how often the background flusher drains WALDir; default 5s.
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// MaxQueuedFiles bounds how many undelivered WAL files may accumulate
+	// before Push starts rejecting new batches with a backpressure error;
+	// default 1000.
+	MaxQueuedFiles int `yaml:"max_queued_files"`
+
+	MaxRetries int           `yaml:"max_retries"`
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
 }
 
 type CommonHTTP struct {
@@ -35,7 +106,7 @@ type GTAConfig struct {
 	// Query parameters (keep simple to start)
 	Since      string `yaml:"since"`       // ISO date/time or relative token handled externally
 	Country    string `yaml:"country"`     // optional filter
-	DateFilter string `yaml:"date_filter"` // announcement_period | update_period | submission_period | in_force_on_date
+	DateFilter string `yaml:"date_filter" jsonschema:"enum=,enum=announcement_period,enum=update_period,enum=submission_period,enum=in_force_on_date"` // announcement_period | update_period | submission_period | in_force_on_date
 	// Incremental sync controls
 	Window    time.Duration `yaml:"window"`     // rolling window (e.g., 168h)
 	StatePath string        `yaml:"state_path"` // persisted cursor file path
@@ -62,12 +133,56 @@ type CoinDeskConfig struct {
 	MaxRetries int           `yaml:"max_retries"`
 	Backoff    time.Duration `yaml:"backoff"`
 	MaxBackoff time.Duration `yaml:"max_backoff"`
+
+	// StreamURL enables the live Stream mode (wss://...); empty disables it
+	// and the scheduler polls Fetch on a schedule as before.
+	StreamURL string `yaml:"stream_url"`
+	// DedupWindow bounds the in-memory LRU of recently-streamed event IDs;
+	// default 10000.
+	DedupWindow int `yaml:"dedup_window"`
+	// PersistEvery/PersistInterval bound how often Stream checkpoints
+	// LastPublished to StatePath; defaults 50 events / 10s, whichever first.
+	PersistEvery    int           `yaml:"persist_every"`
+	PersistInterval time.Duration `yaml:"persist_interval"`
+}
+
+// WebSocketConfig drives the generic websocket Source (config type
+// "websocket"), for push feeds that don't need the source-specific
+// pagination/field-mapping logic CoinDesk and GTA have.
+type WebSocketConfig struct {
+	URL       string `yaml:"url"` // wss://...
+	StatePath string `yaml:"state_path"`
+
+	DedupWindow     int           `yaml:"dedup_window"`
+	PersistEvery    int           `yaml:"persist_every"`
+	PersistInterval time.Duration `yaml:"persist_interval"`
+
+	MaxRetries int           `yaml:"max_retries"`
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+// ScheduleConfig controls how the source.Scheduler runs a single source.
+// Exactly one of Interval/Cron is expected; if both are set, Cron wins.
+type ScheduleConfig struct {
+	Interval      time.Duration `yaml:"interval"`
+	Cron          string        `yaml:"cron"`           // e.g. "*/15 * * * *"
+	Jitter        time.Duration `yaml:"jitter"`         // max random delay added before each run
+	MaxConcurrent int           `yaml:"max_concurrent"` // across all scheduled sources, default 4
+	Timeout       time.Duration `yaml:"timeout"`        // per-fetch context timeout
+	OnError       string        `yaml:"on_error" jsonschema:"enum=,enum=skip,enum=halt,enum=backoff"` // skip (default) | halt | backoff
 }
 
 type SourceConfig struct {
-	Type     string         `yaml:"type"` // "gta"
-	GTA      GTAConfig      `yaml:"gta"`
-	CoinDesk CoinDeskConfig `yaml:"coindesk"`
+	Type      string          `yaml:"type" jsonschema:"enum=gta,enum=coindesk,enum=websocket"` // "gta", "coindesk", or "websocket"
+	GTA       GTAConfig       `yaml:"gta"`
+	CoinDesk  CoinDeskConfig  `yaml:"coindesk"`
+	WebSocket WebSocketConfig `yaml:"websocket"`
+	Schedule  ScheduleConfig  `yaml:"schedule"`
+}
+
+type HealthConfig struct {
+	ListenAddress string `yaml:"listen_address"` // serves /healthz and /readyz; empty disables
 }
 
 type KeywordRule struct {
@@ -76,7 +191,12 @@ type KeywordRule struct {
 }
 
 type RegexRule struct {
-	Field  string            `yaml:"field"` // title|summary|url
+	// Field is matched case-insensitively against one of the built-in
+	// event fields (title|summary|url|lang|country) or, for anything
+	// else, the matching key in the event's Labels map -- see
+	// postprocess.getField. Deliberately left without a jsonschema enum:
+	// the Labels fallback makes this an open set, not a closed one.
+	Field  string            `yaml:"field"`
 	Expr   string            `yaml:"expr"`
 	Labels map[string]string `yaml:"labels"`
 }
@@ -91,26 +211,50 @@ type PostProcessConfig struct {
 	Keywords []KeywordRule `yaml:"keywords"`
 	Regex    []RegexRule   `yaml:"regex"`
 	Maps     []MapRule     `yaml:"maps"`
+
+	// Parallelism is the event-count threshold above which Engine.Apply
+	// fans out across runtime.GOMAXPROCS(0) goroutines instead of running
+	// inline; 0 uses postprocess.DefaultParallelismThreshold.
+	Parallelism int `yaml:"parallelism"`
 }
 
 type MetricsConfig struct {
-	Enable bool          `yaml:"enable"`
+	Enable bool          `yaml:"enable"` // also mounts /metrics on health.listen_address
 	Rollup time.Duration `yaml:"rollup"` // emit counts every run with current timestamp
+
+	// Listen, if set, starts a second /metrics listener on its own address
+	// via metrics.Serve, independent of health.listen_address -- useful
+	// when metrics shouldn't share a port with liveness/readiness checks.
+	Listen string `yaml:"listen"`
 }
 
 type DedupConfig struct {
 	Enable  bool          `yaml:"enable"`
 	TTL     time.Duration `yaml:"ttl"`      // e.g. 168h (7d)
-	MaxKeys int           `yaml:"max_keys"` // cap to bound memory
+	MaxKeys int           `yaml:"max_keys"` // cap to bound memory (memory backend only)
+
+	// Backend selects the persistence strategy: "memory" (default), "bolt",
+	// or "badger+bloom". Persistent backends survive process restarts so a
+	// redeploy doesn't re-emit every event since the last GTA LastAnnounced day.
+	Backend string `yaml:"backend" jsonschema:"enum=,enum=memory,enum=bolt,enum=badger+bloom"`
+	Path    string `yaml:"path"` // on-disk directory/file for bolt/badger
+
+	// Bloom fast-path in front of the persistent store, used by "badger+bloom".
+	BloomExpectedItems uint    `yaml:"bloom_expected_items"`
+	BloomFPRate        float64 `yaml:"bloom_fp_rate"`
+
+	SweepInterval time.Duration `yaml:"sweep_interval"` // background TTL sweep cadence
 }
 
 type Config struct {
 	Loki     LokiConfig        `yaml:"loki"`
 	Victoria VictoriaConfig    `yaml:"victoria"`
+	Kafka    KafkaConfig       `yaml:"kafka"`
 	Sources  []SourceConfig    `yaml:"sources"`
 	Post     PostProcessConfig `yaml:"postprocess"`
 	Metrics  MetricsConfig     `yaml:"metrics"`
 	Dedup    DedupConfig       `yaml:"dedup"`
+	Health   HealthConfig      `yaml:"health"`
 }
 
 func Load(path string) (Config, error) {
@@ -118,12 +262,108 @@ func Load(path string) (Config, error) {
 	if err != nil {
 		return Config{}, err
 	}
+
+	// Validate shape (types, unknown enum values) against the generated
+	// JSON Schema before trying to make sense of it as a Config, so a typo
+	// like `codec: jsom` fails with a schema error instead of silently
+	// decoding into the zero value.
+	var raw interface{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return Config{}, err
+	}
+	fieldErrs, err := validate(raw)
+	if err != nil {
+		return Config{}, err
+	}
+	if len(fieldErrs) > 0 {
+		return Config{}, newValidationError(b, fieldErrs)
+	}
+
 	var c Config
 	if err := yaml.Unmarshal(b, &c); err != nil {
 		return Config{}, err
 	}
-	if c.Loki.URL == "" && c.Victoria.URL == "" {
-		return c, errors.New("need at least one sink (loki or victoria)")
+	if c.Loki.URL == "" && c.Victoria.URL == "" && len(c.Kafka.Brokers) == 0 {
+		return c, errors.New("need at least one sink (loki, victoria, or kafka)")
+	}
+	if c.Dedup.Backend == "" {
+		c.Dedup.Backend = "memory"
+	}
+	if c.Dedup.SweepInterval == 0 {
+		c.Dedup.SweepInterval = 10 * time.Minute
+	}
+	if c.Dedup.BloomExpectedItems == 0 {
+		c.Dedup.BloomExpectedItems = 1_000_000
+	}
+	if c.Dedup.BloomFPRate == 0 {
+		c.Dedup.BloomFPRate = 0.01
 	}
 	return c, nil
 }
+
+// newValidationError aggregates fieldErrs into a single error, annotating
+// each with the YAML line it came from so an operator doesn't have to
+// manually map a JSON pointer like "/sources/1/gta" back to their config
+// file. src is re-parsed into a yaml.Node tree (rather than threading one
+// through from Load) purely to keep Load's happy path -- the common case
+// of a config with no errors -- free of the extra node tree.
+func newValidationError(src []byte, fieldErrs []fieldError) error {
+	var root yaml.Node
+	_ = yaml.Unmarshal(src, &root) // best-effort: line numbers are a diagnostic aid, not required to report the error itself
+
+	msgs := make([]string, len(fieldErrs))
+	for i, fe := range fieldErrs {
+		loc := fe.Pointer
+		if loc == "" {
+			loc = "(root)"
+		}
+		if line, ok := lineForPointer(&root, fe.Pointer); ok {
+			msgs[i] = fmt.Sprintf("line %d: %s: %s", line, loc, fe.Message)
+		} else {
+			msgs[i] = fmt.Sprintf("%s: %s", loc, fe.Message)
+		}
+	}
+	return fmt.Errorf("config: schema validation:\n%s", strings.Join(msgs, "\n"))
+}
+
+// lineForPointer resolves a JSON pointer (as reported by the schema
+// validator against the decoded document, e.g. "/sources/0/gta") to the
+// line of the corresponding node in root, the same document parsed as a
+// yaml.Node tree instead of plain Go values.
+func lineForPointer(root *yaml.Node, pointer string) (int, bool) {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if pointer = strings.TrimPrefix(pointer, "/"); pointer != "" {
+		for _, part := range strings.Split(pointer, "/") {
+			node = stepPointer(node, part)
+			if node == nil {
+				return 0, false
+			}
+		}
+	}
+	return node.Line, true
+}
+
+// stepPointer follows one path segment of a JSON pointer into node,
+// descending into a mapping by key or a sequence by index.
+func stepPointer(node *yaml.Node, key string) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i+1]
+			}
+		}
+		return nil
+	case yaml.SequenceNode:
+		idx, err := strconv.Atoi(key)
+		if err != nil || idx < 0 || idx >= len(node.Content) {
+			return nil
+		}
+		return node.Content[idx]
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_ValidConfig(t *testing.T) {
+	path := writeConfig(t, `
+loki:
+  url: http://loki:3100
+  encoding: json-gzip
+sources:
+  - type: gta
+    gta:
+      base_url: https://api.globaltradealert.org
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Loki.URL != "http://loki:3100" {
+		t.Fatalf("loki.url = %q", cfg.Loki.URL)
+	}
+}
+
+func TestLoad_RejectsUnknownEnumValue(t *testing.T) {
+	path := writeConfig(t, `
+loki:
+  url: http://loki:3100
+  encoding: not-a-real-encoding
+`)
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("expected schema validation error for invalid loki.encoding")
+	}
+	if !strings.Contains(err.Error(), "schema validation") {
+		t.Fatalf("expected schema validation error, got: %v", err)
+	}
+}
+
+func TestLoad_RejectsWrongType(t *testing.T) {
+	path := writeConfig(t, `
+loki:
+  url: http://loki:3100
+  max_retries: "not-a-number"
+`)
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("expected schema validation error for non-integer max_retries")
+	}
+}
+
+func TestLoad_RejectsSourceMissingItsTypeBlock(t *testing.T) {
+	path := writeConfig(t, `
+loki:
+  url: http://loki:3100
+sources:
+  - type: gta
+`)
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("expected schema validation error for a gta source with no gta: block")
+	}
+	if !strings.Contains(err.Error(), "gta") {
+		t.Fatalf("expected error to mention the missing gta block, got: %v", err)
+	}
+}
+
+func TestLoad_RejectsSourceMissingType(t *testing.T) {
+	path := writeConfig(t, `
+loki:
+  url: http://loki:3100
+sources:
+  - gta:
+      base_url: https://api.globaltradealert.org
+`)
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("expected schema validation error for a source with no type:")
+	}
+	if !strings.Contains(err.Error(), "type") {
+		t.Fatalf("expected error to mention the missing type field, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "coindesk") || strings.Contains(err.Error(), "websocket") {
+		t.Fatalf("expected error not to chase the unrelated coindesk/websocket blocks, got: %v", err)
+	}
+}
+
+func TestLoad_ValidationErrorIncludesLineNumber(t *testing.T) {
+	path := writeConfig(t, `
+loki:
+  url: http://loki:3100
+  encoding: not-a-real-encoding
+`)
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("expected schema validation error for invalid loki.encoding")
+	}
+	if !strings.Contains(err.Error(), "line 4") {
+		t.Fatalf("expected error to point at line 4 (the encoding: line), got: %v", err)
+	}
+}
+
+func TestWriteSchema_MatchesSchema(t *testing.T) {
+	want, err := Schema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteSchema(&buf); err != nil {
+		t.Fatalf("WriteSchema: %v", err)
+	}
+	if buf.String() != string(want) {
+		t.Fatalf("WriteSchema() wrote a different document than Schema() returned")
+	}
+}
+
+func TestSchema_IsStableAndCompiles(t *testing.T) {
+	b, err := Schema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(b) == 0 {
+		t.Fatal("expected non-empty schema document")
+	}
+	b2, err := Schema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(b) != string(b2) {
+		t.Fatal("Schema() should return the same cached document across calls")
+	}
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/invopop/jsonschema"
+	jsonschemav "github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// schemaOnce builds and compiles the JSON Schema for Config exactly once;
+// the reflection walk and schema compile are a few milliseconds but there's
+// no reason to redo them on every Load.
+var (
+	schemaOnce     sync.Once
+	schemaDoc      []byte
+	compiledSchema *jsonschemav.Schema
+	schemaErr      error
+)
+
+// Schema returns the JSON Schema document describing Config, reflected
+// straight off the struct's `yaml` tags (plus any `jsonschema:"enum=..."`
+// constraints on individual fields) so it can't drift from the Go types it
+// documents. Exposed for tooling -- e.g. `multi-ingester -print-schema`.
+func Schema() ([]byte, error) {
+	schemaOnce.Do(buildSchema)
+	return schemaDoc, schemaErr
+}
+
+// WriteSchema writes the Config JSON Schema to w. It's the plumbing behind
+// `multi-ingester -print-schema`, but exported so anything that wants the
+// schema on disk (e.g. to point an editor's YAML language server at it)
+// doesn't have to shell out to the binary to get it.
+func WriteSchema(w io.Writer) error {
+	b, err := Schema()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func buildSchema() {
+	r := &jsonschema.Reflector{
+		FieldNameTag:   "yaml",
+		DoNotReference: true,
+		// Every Config field is optional (the YAML unmarshal leaves zero
+		// values for anything unset, and Load fills in defaults for the
+		// few that need them) -- without this, the reflector would mark
+		// every field lacking a yaml "omitempty" option as required, and
+		// reject any config that doesn't spell out the whole struct.
+		RequiredFromJSONSchemaTags: true,
+	}
+	s := r.Reflect(&Config{})
+	b, err := json.Marshal(s)
+	if err != nil {
+		schemaErr = fmt.Errorf("config: marshal schema: %w", err)
+		return
+	}
+
+	// The reflector has no notion that SourceConfig.Type selects which of
+	// GTA/CoinDesk/WebSocket actually gets used, so bolt on the per-type
+	// requirement by hand: a "gta" source needs its gta: block, etc.
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		schemaErr = fmt.Errorf("config: decode reflected schema: %w", err)
+		return
+	}
+	if err := addSourceTypeRequirements(doc); err != nil {
+		schemaErr = fmt.Errorf("config: add source type requirements: %w", err)
+		return
+	}
+	b, err = json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		schemaErr = fmt.Errorf("config: marshal schema: %w", err)
+		return
+	}
+	schemaDoc = b
+
+	c := jsonschemav.NewCompiler()
+	if err := c.AddResource("config.schema.json", bytes.NewReader(b)); err != nil {
+		schemaErr = fmt.Errorf("config: add schema resource: %w", err)
+		return
+	}
+	sch, err := c.Compile("config.schema.json")
+	if err != nil {
+		schemaErr = fmt.Errorf("config: compile schema: %w", err)
+		return
+	}
+	compiledSchema = sch
+}
+
+// addSourceTypeRequirements mutates the reflected schema's sources[].items
+// node, adding an allOf/if/then per SourceConfig.Type value so that e.g.
+// "type: gta" without a gta: block fails validation instead of silently
+// running with a zero-value GTAConfig.
+func addSourceTypeRequirements(doc map[string]interface{}) error {
+	props, ok := doc["properties"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("schema has no top-level properties")
+	}
+	sources, ok := props["sources"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("schema missing sources property")
+	}
+	items, ok := sources["items"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("sources.items is not an inlined object schema")
+	}
+
+	// "type" itself must come first and be required unconditionally: each
+	// "if" below also requires "type" (a bare properties.type.const would be
+	// vacuously true when "type" is absent), so an omitted "type" matches no
+	// "if" and trips no "then" clause -- without this top-level requirement
+	// a source with no "type" would pass validation.
+	required := []string{"type"}
+	switch existing := items["required"].(type) {
+	case []string:
+		required = append(required, existing...)
+	case []interface{}:
+		for _, v := range existing {
+			if s, ok := v.(string); ok {
+				required = append(required, s)
+			}
+		}
+	}
+	items["required"] = required
+
+	var allOf []interface{}
+	for _, sourceType := range []string{"gta", "coindesk", "websocket"} {
+		allOf = append(allOf, map[string]interface{}{
+			"if": map[string]interface{}{
+				"required": []string{"type"},
+				"properties": map[string]interface{}{
+					"type": map[string]interface{}{"const": sourceType},
+				},
+			},
+			"then": map[string]interface{}{
+				"required": []string{sourceType},
+			},
+		})
+	}
+	items["allOf"] = allOf
+	return nil
+}
+
+// fieldError is one schema validation failure, identified by a JSON
+// pointer into the document (e.g. "/sources/0/gta") rather than a line
+// number -- resolving that pointer against the parsed YAML is left to
+// newValidationError, which re-parses the source into the yaml.Node tree
+// the pointer needs to walk.
+type fieldError struct {
+	Pointer string
+	Message string
+}
+
+// validate checks raw -- the config YAML decoded into plain
+// maps/slices/scalars -- against the generated schema, returning every
+// leaf validation failure instead of stopping at the first one. raw comes
+// from yaml.v3, which decodes into native Go ints/floats; santhosh-tekuri
+// validates against encoding/json-shaped values (numbers as float64), so we
+// round-trip through JSON first.
+func validate(raw interface{}) ([]fieldError, error) {
+	if _, err := Schema(); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("config: marshal for validation: %w", err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("config: unmarshal for validation: %w", err)
+	}
+	if err := compiledSchema.Validate(doc); err != nil {
+		ve, ok := err.(*jsonschemav.ValidationError)
+		if !ok {
+			return nil, fmt.Errorf("config: schema validation: %w", err)
+		}
+		return flattenValidationError(ve), nil
+	}
+	return nil, nil
+}
+
+// flattenValidationError walks a (possibly deeply nested) ValidationError
+// tree down to its leaves -- the causes that actually name a field, rather
+// than the wrapping "doesn't match allOf/oneOf" errors santhosh-tekuri
+// reports at each level above them.
+func flattenValidationError(ve *jsonschemav.ValidationError) []fieldError {
+	if len(ve.Causes) == 0 {
+		return []fieldError{{Pointer: ve.InstanceLocation, Message: ve.Message}}
+	}
+	var out []fieldError
+	for _, cause := range ve.Causes {
+		out = append(out, flattenValidationError(cause)...)
+	}
+	return out
+}
@@ -2,23 +2,54 @@ package metrics
 
 import (
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type labelKey string
 
-type counterVec struct {
-	mu   sync.Mutex
-	data map[labelKey]float64
+// metricVec is one named counter or gauge, tracked two ways: the plain
+// in-memory map that backs Dump() (which always has a value for every
+// label-set ever seen, with no cardinality limit imposed by Prometheus
+// client types), and a lazily (re)built Prometheus vector that backs the
+// scraped /metrics endpoint.
+type metricVec struct {
+	mu      sync.Mutex
+	data    map[labelKey]float64
+	isGauge bool
+
+	// labelNames is the sorted union of label names seen so far for this
+	// metric. promVec is built against exactly these names; a call with a
+	// label name outside this set grows the union and rebuilds the vec
+	// from scratch (Prometheus vectors have a fixed label schema), then
+	// replays every previously recorded label-set into it, filling "" for
+	// the name(s) that label-set didn't carry.
+	labelNames []string
+	gaugeVec   *prometheus.GaugeVec
+	counterVec *prometheus.CounterVec
+
+	// promTotal tracks the cumulative value last pushed into counterVec
+	// for each label-set. prometheus.Counter only exposes Add/Inc (no
+	// Set), while mv.data holds the running total rather than a per-call
+	// delta, so pushPromValue diffs against this to find the Add amount.
+	// Reset whenever counterVec is (re)built, since the fresh vec starts
+	// every series at zero.
+	promTotal map[labelKey]float64
 }
 
 var (
 	counters = struct {
 		mu sync.Mutex
-		m  map[string]*counterVec
-	}{m: make(map[string]*counterVec)}
+		m  map[string]*metricVec
+	}{m: make(map[string]*metricVec)}
+
+	registry = prometheus.NewRegistry()
 )
 
 func keyFromLabels(labels map[string]string) labelKey {
@@ -42,34 +73,179 @@ func keyFromLabels(labels map[string]string) labelKey {
 	return labelKey(b.String())
 }
 
-// IncCounter increments a named counter with a set of labels by v.
-func IncCounter(name string, labels map[string]string, v float64) {
+// labelsFromKey reverses keyFromLabels; rebuildPromVec uses it to replay a
+// previously recorded label-set into a freshly (re)built vector.
+func labelsFromKey(lk labelKey) map[string]string {
+	out := make(map[string]string)
+	if lk == "" {
+		return out
+	}
+	for _, pair := range strings.Split(string(lk), ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func getOrCreate(name string) *metricVec {
 	counters.mu.Lock()
-	cv, ok := counters.m[name]
+	defer counters.mu.Unlock()
+	mv, ok := counters.m[name]
 	if !ok {
-		cv = &counterVec{data: make(map[labelKey]float64)}
-		counters.m[name] = cv
+		mv = &metricVec{data: make(map[labelKey]float64), promTotal: make(map[labelKey]float64)}
+		counters.m[name] = mv
+	}
+	return mv
+}
+
+func labelNamesOf(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rebuildPromVec ensures mv's Prometheus vector exists, (re)creating it against
+// the union of mv.labelNames and labelNames if that union grows the
+// schema, and replaying every previously recorded value into the fresh
+// vector. Callers must hold mv.mu.
+func (mv *metricVec) rebuildPromVec(name string, labelNames []string) {
+	union := make(map[string]struct{}, len(mv.labelNames)+len(labelNames))
+	for _, n := range mv.labelNames {
+		union[n] = struct{}{}
+	}
+	for _, n := range labelNames {
+		union[n] = struct{}{}
+	}
+	if len(union) == len(mv.labelNames) && (mv.gaugeVec != nil || mv.counterVec != nil) {
+		return // schema unchanged, existing vec already covers it
+	}
+
+	sorted := make([]string, 0, len(union))
+	for n := range union {
+		sorted = append(sorted, n)
+	}
+	sort.Strings(sorted)
+
+	if mv.gaugeVec != nil {
+		registry.Unregister(mv.gaugeVec)
+	}
+	if mv.counterVec != nil {
+		registry.Unregister(mv.counterVec)
 	}
-	counters.mu.Unlock()
 
+	if mv.isGauge {
+		mv.gaugeVec = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: name}, sorted)
+		registry.MustRegister(mv.gaugeVec)
+	} else {
+		mv.counterVec = prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: name}, sorted)
+		registry.MustRegister(mv.counterVec)
+		mv.promTotal = make(map[labelKey]float64)
+	}
+	mv.labelNames = sorted
+
+	for lk, v := range mv.data {
+		mv.pushPromValue(lk, v)
+	}
+}
+
+// pushPromValue writes v into the current vec for label-set lk, filling in
+// "" for any label name in the vec's schema that lk doesn't carry (only
+// possible right after a rebuild, backfilling older, narrower label-sets).
+// Gauges take v directly via Set; prometheus.Counter only exposes Add/Inc,
+// so counters diff v against promTotal[lk] and Add the delta. Callers must
+// hold mv.mu.
+func (mv *metricVec) pushPromValue(lk labelKey, v float64) {
+	labels := labelsFromKey(lk)
+	values := make([]string, len(mv.labelNames))
+	for i, n := range mv.labelNames {
+		values[i] = labels[n]
+	}
+	if mv.isGauge {
+		mv.gaugeVec.WithLabelValues(values...).Set(v)
+		return
+	}
+	if delta := v - mv.promTotal[lk]; delta > 0 {
+		mv.counterVec.WithLabelValues(values...).Add(delta)
+	}
+	mv.promTotal[lk] = v
+}
+
+// IncCounter increments a named counter with a set of labels by v.
+func IncCounter(name string, labels map[string]string, v float64) {
+	mv := getOrCreate(name)
+	lk := keyFromLabels(labels)
+
+	mv.mu.Lock()
+	defer mv.mu.Unlock()
+	mv.isGauge = false
+	mv.data[lk] += v
+	mv.rebuildPromVec(name, labelNamesOf(labels))
+	mv.pushPromValue(lk, mv.data[lk])
+}
+
+// SetGauge sets a named gauge with a set of labels to v, overwriting any
+// previous value (unlike IncCounter, which accumulates).
+func SetGauge(name string, labels map[string]string, v float64) {
+	mv := getOrCreate(name)
 	lk := keyFromLabels(labels)
-	cv.mu.Lock()
-	defer cv.mu.Unlock()
-	cv.data[lk] += v
+
+	mv.mu.Lock()
+	defer mv.mu.Unlock()
+	mv.isGauge = true
+	mv.data[lk] = v
+	mv.rebuildPromVec(name, labelNamesOf(labels))
+	mv.pushPromValue(lk, v)
 }
 
-// Dump returns a human-readable snapshot of counters (for logging).
-func Dump() string {
+// lines returns a sorted "name{labels} value" line per label-set, across
+// every registered counter/gauge.
+func lines() []string {
 	counters.mu.Lock()
 	defer counters.mu.Unlock()
 	var out []string
-	for name, cv := range counters.m {
-		cv.mu.Lock()
-		for lk, v := range cv.data {
+	for name, mv := range counters.m {
+		mv.mu.Lock()
+		for lk, v := range mv.data {
 			out = append(out, fmt.Sprintf("%s{%s} %g", name, string(lk), v))
 		}
-		cv.mu.Unlock()
+		mv.mu.Unlock()
 	}
 	sort.Strings(out)
-	return strings.Join(out, "")
+	return out
+}
+
+// Dump returns a human-readable, single-line snapshot of counters and gauges (for
+// logging); see Handler for the Prometheus exposition form scraped by
+// /metrics.
+func Dump() string {
+	return strings.Join(lines(), " ")
+}
+
+// Handler serves every registered counter/gauge in the Prometheus text
+// exposition format, with HELP/TYPE metadata, via promhttp.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Serve starts a standalone HTTP server exposing Handler at /metrics on
+// addr. It blocks like http.ListenAndServe, so callers run it in a
+// goroutine; this is separate from mounting Handler on an existing mux
+// (e.g. the health server's), for deployments that want metrics on their
+// own port.
+func Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      mux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+	return server.ListenAndServe()
 }
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIncCounter_AccumulatesAndExposesViaHandler(t *testing.T) {
+	name := "test_requests_total"
+	IncCounter(name, map[string]string{"source": "gta"}, 2)
+	IncCounter(name, map[string]string{"source": "gta"}, 3)
+
+	if got := Dump(); !strings.Contains(got, name+"{source=gta} 5") {
+		t.Fatalf("Dump() = %q, want it to contain %q", got, name+"{source=gta} 5")
+	}
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, name+`{source="gta"} 5`) {
+		t.Fatalf("scraped body = %q, want it to contain cumulative counter value 5", body)
+	}
+}
+
+func TestSetGauge_OverwritesRatherThanAccumulates(t *testing.T) {
+	name := "test_queue_depth"
+	SetGauge(name, map[string]string{"source": "news"}, 7)
+	SetGauge(name, map[string]string{"source": "news"}, 4)
+
+	if got := Dump(); !strings.Contains(got, name+"{source=news} 4") {
+		t.Fatalf("Dump() = %q, want latest gauge value 4, not accumulated", got)
+	}
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, name+`{source="news"} 4`) {
+		t.Fatalf("scraped body = %q, want it to contain gauge value 4", body)
+	}
+}
@@ -2,67 +2,54 @@ package postprocess
 
 import (
 	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 
 	"time-value-analyser/multi-ingester/internal/config"
 	"time-value-analyser/multi-ingester/internal/model"
 )
 
-type Engine struct {
-	kw   []config.KeywordRule
-	regs []compiledRegex
-	maps []config.MapRule
+// DefaultParallelismThreshold is the event count above which Apply fans out
+// across goroutines when PostProcessConfig.Parallelism is unset (0).
+const DefaultParallelismThreshold = 100
+
+type keywordRule struct {
+	words  []string
+	labels map[string]string
 }
 
-type compiledRegex struct {
+type regexRule struct {
 	field  string
 	re     *regexp.Regexp
 	labels map[string]string
 }
 
-func New(cfg config.PostProcessConfig) (*Engine, error) {
-	eng := &Engine{kw: cfg.Keywords, maps: cfg.Maps}
-	for _, r := range cfg.Regex {
-		re, err := regexp.Compile(r.Expr)
-		if err != nil {
-			return nil, err
-		}
-		eng.regs = append(eng.regs, compiledRegex{field: r.Field, re: re, labels: r.Labels})
-	}
-	return eng, nil
+type mapRule struct {
+	field   string
+	outKey  string
+	mapping map[string]string
 }
 
-// Apply runs keyword, regex, and mapping rules over events and returns the mutated slice.
-// If cfg has no rules, Apply returns the original events.
-func Apply(events []model.Event, cfg config.PostProcessConfig) []model.Event {
-	if len(events) == 0 {
-		return events
-	}
+// Engine holds compiled/normalized keyword, regex, and map rules, built
+// once by New so Apply doesn't recompile a regexp or re-normalize a
+// keyword list on every batch.
+type Engine struct {
+	kw    []keywordRule
+	regs  []regexRule
+	maps  []mapRule
+	limit int // event count above which Apply parallelizes
+}
 
-	// Pre-compile regex rules once
-	type ritem struct {
-		field  string
-		re     *regexp.Regexp
-		labels map[string]string
-	}
-	var rrules []ritem
-	for _, rr := range cfg.Regex {
-		if strings.TrimSpace(rr.Field) == "" || strings.TrimSpace(rr.Expr) == "" {
-			continue
-		}
-		re, err := regexp.Compile(rr.Expr)
-		if err != nil {
-			continue
-		}
-		rrules = append(rrules, ritem{field: rr.Field, re: re, labels: rr.Labels})
+// New compiles cfg's rules once. Malformed rules (missing field/expr,
+// uncompilable regex, empty keyword list) are skipped rather than erroring,
+// matching Apply's previous inline behavior.
+func New(cfg config.PostProcessConfig) (*Engine, error) {
+	eng := &Engine{limit: cfg.Parallelism}
+	if eng.limit <= 0 {
+		eng.limit = DefaultParallelismThreshold
 	}
 
-	// Normalize keyword rules
-	type kitem struct {
-		words  []string
-		labels map[string]string
-	}
-	var krules []kitem
 	for _, kr := range cfg.Keywords {
 		if len(kr.When) == 0 {
 			continue
@@ -76,16 +63,20 @@ func Apply(events []model.Event, cfg config.PostProcessConfig) []model.Event {
 		if len(words) == 0 {
 			continue
 		}
-		krules = append(krules, kitem{words: words, labels: kr.Labels})
+		eng.kw = append(eng.kw, keywordRule{words: words, labels: kr.Labels})
 	}
 
-	// Map rules (simple value-to-label mapping for a given event field)
-	type mitem struct {
-		field   string
-		outKey  string
-		mapping map[string]string
+	for _, rr := range cfg.Regex {
+		if strings.TrimSpace(rr.Field) == "" || strings.TrimSpace(rr.Expr) == "" {
+			continue
+		}
+		re, err := regexp.Compile(rr.Expr)
+		if err != nil {
+			continue
+		}
+		eng.regs = append(eng.regs, regexRule{field: rr.Field, re: re, labels: rr.Labels})
 	}
-	var mrules []mitem
+
 	for _, mr := range cfg.Maps {
 		if strings.TrimSpace(mr.Field) == "" || len(mr.Mapping) == 0 {
 			continue
@@ -94,83 +85,131 @@ func Apply(events []model.Event, cfg config.PostProcessConfig) []model.Event {
 		if out == "" {
 			out = mr.Field
 		}
-		mrules = append(mrules, mitem{field: mr.Field, outKey: out, mapping: mr.Mapping})
+		eng.maps = append(eng.maps, mapRule{field: mr.Field, outKey: out, mapping: mr.Mapping})
 	}
 
-	// helper to get a string field from Event for rule evaluation
-	getField := func(e *model.Event, name string) string {
-		switch strings.ToLower(name) {
-		case "title":
-			return e.Title
-		case "summary":
-			return e.Summary
-		case "url":
-			return e.URL
-		case "lang", "language":
-			return e.Lang
-		case "country":
-			return e.Country
-		default:
-			// try label bag for arbitrary fields
-			if v, ok := e.Labels[name]; ok {
-				return v
-			}
-			return ""
+	return eng, nil
+}
+
+// Apply runs keyword, regex, and mapping rules over events in place and
+// returns the same slice. Below the Engine's parallelism threshold it
+// processes events inline; above it, it splits events into
+// runtime.GOMAXPROCS(0) chunks and processes each chunk in its own
+// goroutine, which is safe because every event's Labels map is its own --
+// no two chunks ever write into the same map.
+func (eng *Engine) Apply(events []model.Event) []model.Event {
+	if len(events) == 0 || (len(eng.kw) == 0 && len(eng.regs) == 0 && len(eng.maps) == 0) {
+		return events
+	}
+
+	if len(events) <= eng.limit {
+		for i := range events {
+			eng.applyOne(&events[i])
 		}
+		return events
 	}
 
-	out := make([]model.Event, 0, len(events))
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(events) {
+		workers = len(events)
+	}
+	chunk := (len(events) + workers - 1) / workers
 
-	for _, ev := range events {
-		// Ensure Labels map is non-nil
-		if ev.Labels == nil {
-			ev.Labels = make(map[string]string, 4)
+	var wg sync.WaitGroup
+	for start := 0; start < len(events); start += chunk {
+		end := start + chunk
+		if end > len(events) {
+			end = len(events)
 		}
+		wg.Add(1)
+		go func(sub []model.Event) {
+			defer wg.Done()
+			for i := range sub {
+				eng.applyOne(&sub[i])
+			}
+		}(events[start:end])
+	}
+	wg.Wait()
+
+	return events
+}
+
+// applyOne mutates ev in place: ensures a non-nil Labels map, then runs
+// keyword, regex, and map rules against it in that order.
+func (eng *Engine) applyOne(ev *model.Event) {
+	if ev.Labels == nil {
+		ev.Labels = make(map[string]string, 4)
+	}
 
-		// 1) Keyword rules: if ALL words appear in title or summary (case-insensitive), apply labels
-		titleLC := strings.ToLower(ev.Title)
-		sumLC := strings.ToLower(ev.Summary)
-		for _, kr := range krules {
-			matched := true
-			for _, w := range kr.words {
-				if !strings.Contains(titleLC, w) && !strings.Contains(sumLC, w) {
-					matched = false
-					break
-				}
+	titleLC := strings.ToLower(ev.Title)
+	sumLC := strings.ToLower(ev.Summary)
+	for _, kr := range eng.kw {
+		matched := true
+		for _, w := range kr.words {
+			if !strings.Contains(titleLC, w) && !strings.Contains(sumLC, w) {
+				matched = false
+				break
 			}
-			if matched {
-				for k, v := range kr.labels {
-					ev.Labels[k] = v
-				}
+		}
+		if matched {
+			for k, v := range kr.labels {
+				ev.Labels[k] = v
 			}
 		}
+	}
 
-		// 2) Regex rules: run against specified field
-		for _, rr := range rrules {
-			val := getField(&ev, rr.field)
-			if val == "" {
-				continue
-			}
-			if rr.re.MatchString(val) {
-				for k, v := range rr.labels {
-					ev.Labels[k] = v
-				}
+	for _, rr := range eng.regs {
+		val := getField(ev, rr.field)
+		if val == "" {
+			continue
+		}
+		if rr.re.MatchString(val) {
+			for k, v := range rr.labels {
+				ev.Labels[k] = v
 			}
 		}
+	}
 
-		// 3) Map rules: if field value has a mapping, set the mapped label
-		for _, mr := range mrules {
-			val := getField(&ev, mr.field)
-			if val == "" {
-				continue
-			}
-			if mapped, ok := mr.mapping[val]; ok {
-				ev.Labels[mr.outKey] = mapped
-			}
+	for _, mr := range eng.maps {
+		val := getField(ev, mr.field)
+		if val == "" {
+			continue
 		}
+		if mapped, ok := mr.mapping[val]; ok {
+			ev.Labels[mr.outKey] = mapped
+		}
+	}
+}
 
-		out = append(out, ev)
+// getField reads a string field off ev for rule evaluation, falling back
+// to the Labels bag for arbitrary field names.
+func getField(ev *model.Event, name string) string {
+	switch strings.ToLower(name) {
+	case "title":
+		return ev.Title
+	case "summary":
+		return ev.Summary
+	case "url":
+		return ev.URL
+	case "lang", "language":
+		return ev.Lang
+	case "country":
+		return ev.Country
+	default:
+		if v, ok := ev.Labels[name]; ok {
+			return v
+		}
+		return ""
 	}
+}
 
-	return out
+// Apply is a convenience wrapper for callers that don't keep an Engine
+// around; it compiles cfg's rules on every call, so any hot path should
+// build an Engine with New once and call (*Engine).Apply instead.
+func Apply(events []model.Event, cfg config.PostProcessConfig) []model.Event {
+	eng, err := New(cfg)
+	if err != nil {
+		return events
+	}
+	return eng.Apply(events)
 }
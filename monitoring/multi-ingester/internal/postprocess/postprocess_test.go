@@ -0,0 +1,154 @@
+package postprocess
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"time-value-analyser/multi-ingester/internal/config"
+	"time-value-analyser/multi-ingester/internal/model"
+)
+
+func benchConfig() config.PostProcessConfig {
+	return config.PostProcessConfig{
+		Keywords: []config.KeywordRule{
+			{When: []string{"breach", "leak"}, Labels: map[string]string{"severity": "high"}},
+		},
+		Regex: []config.RegexRule{
+			{Field: "url", Expr: `\.gov$`, Labels: map[string]string{"sector": "public"}},
+		},
+		Maps: []config.MapRule{
+			{Field: "country", OutKey: "region", Mapping: map[string]string{"DE": "EU", "FR": "EU", "US": "NA"}},
+		},
+	}
+}
+
+func benchEvents(n int) []model.Event {
+	countries := []string{"DE", "FR", "US"}
+	evs := make([]model.Event, n)
+	for i := range evs {
+		evs[i] = model.Event{
+			ID:      fmt.Sprintf("ev-%d", i),
+			Source:  "bench",
+			Title:   "major data breach disclosed",
+			Summary: "a leak affecting several systems",
+			URL:     "https://example.gov",
+			Country: countries[i%len(countries)],
+		}
+	}
+	return evs
+}
+
+// vector is the JSON shape of one conformance test vector: a rule config,
+// the events fed into Apply, and the events (specifically their Labels)
+// expected to come out the other side.
+type vector struct {
+	Config   config.PostProcessConfig `json:"config"`
+	Input    []model.Event            `json:"input"`
+	Expected []model.Event            `json:"expected"`
+}
+
+// TestVectors replays every JSON file under vectors/ against Apply and
+// asserts the resulting Labels match, including that pre-existing labels
+// survive and that map rules fall back to Field when OutKey is unset. This
+// corpus is the rule engine's stable contract -- any future rule engine
+// (e.g. CEL-based) should be validated against the same files.
+func TestVectors(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("vectors", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no vector files found under vectors/")
+	}
+
+	for _, f := range files {
+		f := f
+		t.Run(filepath.Base(f), func(t *testing.T) {
+			raw, err := os.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var v vector
+			if err := json.Unmarshal(raw, &v); err != nil {
+				t.Fatalf("decode %s: %v", f, err)
+			}
+			if len(v.Input) != len(v.Expected) {
+				t.Fatalf("%s: input has %d events, expected has %d", f, len(v.Input), len(v.Expected))
+			}
+
+			eng, err := New(v.Config)
+			if err != nil {
+				t.Fatalf("%s: New: %v", f, err)
+			}
+			got := eng.Apply(v.Input)
+			for i := range got {
+				if !reflect.DeepEqual(got[i].Labels, v.Expected[i].Labels) {
+					t.Errorf("%s: event %d (%s): Labels = %#v, want %#v", f, i, got[i].ID, got[i].Labels, v.Expected[i].Labels)
+				}
+			}
+		})
+	}
+}
+
+// TestApply_ParallelPathMatchesInlinePathAboveThreshold exercises the
+// goroutine fan-out Apply switches to above DefaultParallelismThreshold,
+// asserting every event gets exactly the labels the inline path (run here
+// on an identical copy, below the threshold) would have produced. Run with
+// -race, since this is the only test that actually crosses the threshold
+// and touches the worker-pool code path; BenchmarkApply only times it.
+func TestApply_ParallelPathMatchesInlinePathAboveThreshold(t *testing.T) {
+	const n = 10 * DefaultParallelismThreshold
+
+	eng, err := New(benchConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parallel := benchEvents(n)
+	if len(parallel) <= eng.limit {
+		t.Fatalf("test setup: %d events must exceed the parallelism threshold %d", n, eng.limit)
+	}
+	got := eng.Apply(parallel)
+
+	inlineEng, err := New(benchConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	inlineEng.limit = n + 1 // force the inline path regardless of DefaultParallelismThreshold
+	want := inlineEng.Apply(benchEvents(n))
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if !reflect.DeepEqual(got[i].Labels, want[i].Labels) {
+			t.Fatalf("event %d (%s): parallel Labels = %#v, want %#v (inline)", i, got[i].ID, got[i].Labels, want[i].Labels)
+		}
+	}
+}
+
+// BenchmarkApply demonstrates the crossover between Engine.Apply running
+// inline and fanning out across goroutines: event counts straddle
+// DefaultParallelismThreshold (100) on either side.
+func BenchmarkApply(b *testing.B) {
+	for _, n := range []int{10, DefaultParallelismThreshold, 10 * DefaultParallelismThreshold} {
+		b.Run(fmt.Sprintf("events=%d", n), func(b *testing.B) {
+			eng, err := New(benchConfig())
+			if err != nil {
+				b.Fatal(err)
+			}
+			events := benchEvents(n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := range events {
+					events[j].Labels = nil
+				}
+				eng.Apply(events)
+			}
+		})
+	}
+}
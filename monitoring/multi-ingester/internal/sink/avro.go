@@ -0,0 +1,24 @@
+package sink
+
+import (
+	"github.com/hamba/avro/v2"
+
+	"time-value-analyser/multi-ingester/internal/model"
+)
+
+// avroEncoder marshals a model.Event against a fixed Avro schema.
+type avroEncoder struct {
+	schema avro.Schema
+}
+
+func newAvroEncoder(schemaJSON string) (*avroEncoder, error) {
+	schema, err := avro.Parse(schemaJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &avroEncoder{schema: schema}, nil
+}
+
+func (e *avroEncoder) Encode(ev model.Event) ([]byte, error) {
+	return avro.Marshal(e.schema, ev)
+}
@@ -0,0 +1,191 @@
+package sink
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"time-value-analyser/multi-ingester/internal/config"
+	"time-value-analyser/multi-ingester/internal/model"
+)
+
+// kafkaSink publishes events as Kafka messages, one message per event, so
+// this can be the first stage of a Kafka-based analytics pipeline instead of
+// being Loki-only.
+type kafkaSink struct {
+	cfg    config.KafkaConfig
+	client *kgo.Client
+	codec  valueCodec
+}
+
+// valueCodec encodes a model.Event's value bytes for the chosen Codec.
+type valueCodec interface {
+	Encode(topic string, e model.Event) ([]byte, error)
+}
+
+func NewKafka(cfg config.KafkaConfig) (Sink, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka: brokers is required")
+	}
+	if cfg.TopicTemplate == "" {
+		cfg.TopicTemplate = "events.{source}"
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.ProducerLinger(time.Duration(cfg.LingerMs) * time.Millisecond),
+	}
+	if cfg.MaxBatchBytes > 0 {
+		opts = append(opts, kgo.ProducerBatchMaxBytes(int32(cfg.MaxBatchBytes)))
+	}
+	if cfg.Idempotent {
+		// franz-go producers are idempotent by default; just require
+		// every in-sync replica to ack before the write is durable.
+		opts = append(opts, kgo.RequiredAcks(kgo.AllISRAcks()))
+	} else {
+		opts = append(opts, kgo.DisableIdempotentWrite())
+	}
+	if cfg.Timeout > 0 {
+		opts = append(opts, kgo.ProduceRequestTimeout(cfg.Timeout))
+	}
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: new client: %w", err)
+	}
+
+	codec, err := newValueCodec(cfg)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &kafkaSink{cfg: cfg, client: client, codec: codec}, nil
+}
+
+func (k *kafkaSink) Name() string { return "kafka" }
+
+func (k *kafkaSink) Push(ctx context.Context, events []model.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var gotErr error
+	results := make(chan error, len(events))
+	for _, e := range events {
+		topic := renderTopic(k.cfg.TopicTemplate, e.Source)
+		val, err := k.codec.Encode(topic, e)
+		if err != nil {
+			return fmt.Errorf("kafka: encode %s/%s: %w", e.Source, e.ID, err)
+		}
+		rec := &kgo.Record{
+			Topic:   topic,
+			Key:     []byte(e.Source + ":" + e.ID),
+			Value:   val,
+			Headers: headersFromLabels(e.Labels),
+		}
+		k.client.Produce(ctx, rec, func(_ *kgo.Record, err error) {
+			results <- err
+		})
+	}
+	for i := 0; i < len(events); i++ {
+		if err := <-results; err != nil && gotErr == nil {
+			gotErr = fmt.Errorf("kafka produce: %w", err)
+		}
+	}
+	return gotErr
+}
+
+func renderTopic(tmpl, source string) string {
+	return strings.ReplaceAll(tmpl, "{source}", source)
+}
+
+func headersFromLabels(labels map[string]string) []kgo.RecordHeader {
+	if len(labels) == 0 {
+		return nil
+	}
+	hdrs := make([]kgo.RecordHeader, 0, len(labels))
+	for k, v := range labels {
+		hdrs = append(hdrs, kgo.RecordHeader{Key: k, Value: []byte(v)})
+	}
+	return hdrs
+}
+
+func newValueCodec(cfg config.KafkaConfig) (valueCodec, error) {
+	switch cfg.Codec {
+	case "", "json":
+		return jsonCodec{}, nil
+	case "json-schema":
+		return newJSONSchemaCodec(cfg.SchemaRegistry)
+	case "avro":
+		return newAvroCodec(cfg.AvroSchema, cfg.SchemaRegistry)
+	default:
+		return nil, fmt.Errorf("kafka: unknown codec %q", cfg.Codec)
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(_ string, e model.Event) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// confluentSchemaCodec wraps a Confluent Schema Registry wire-format payload:
+// 1 magic byte (0x0) + 4-byte big-endian schema id + the encoded payload.
+type confluentSchemaCodec struct {
+	registry config.SchemaRegistryConfig
+	encode   func(e model.Event) ([]byte, error)
+	schemaID func(topic string) (int32, error)
+}
+
+func (c *confluentSchemaCodec) Encode(topic string, e model.Event) ([]byte, error) {
+	id, err := c.schemaID(topic)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: lookup schema id: %w", err)
+	}
+	payload, err := c.encode(e)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 5+len(payload))
+	buf[0] = 0x0
+	binary.BigEndian.PutUint32(buf[1:5], uint32(id))
+	copy(buf[5:], payload)
+	return buf, nil
+}
+
+func newJSONSchemaCodec(reg config.SchemaRegistryConfig) (valueCodec, error) {
+	if reg.URL == "" {
+		return nil, fmt.Errorf("kafka: schema_registry.url is required for codec json-schema")
+	}
+	client := newSchemaRegistryClient(reg)
+	return &confluentSchemaCodec{
+		registry: reg,
+		encode:   func(e model.Event) ([]byte, error) { return json.Marshal(e) },
+		schemaID: client.idForSubject,
+	}, nil
+}
+
+func newAvroCodec(schema string, reg config.SchemaRegistryConfig) (valueCodec, error) {
+	if schema == "" {
+		return nil, fmt.Errorf("kafka: avro_schema is required for codec avro")
+	}
+	if reg.URL == "" {
+		return nil, fmt.Errorf("kafka: schema_registry.url is required for codec avro")
+	}
+	enc, err := newAvroEncoder(schema)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: parse avro schema: %w", err)
+	}
+	client := newSchemaRegistryClient(reg)
+	return &confluentSchemaCodec{
+		registry: reg,
+		encode:   enc.Encode,
+		schemaID: client.idForSubject,
+	}, nil
+}
@@ -2,45 +2,70 @@ package sink
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/golang/snappy"
+	"github.com/grafana/loki/pkg/push"
+
 	"time-value-analyser/multi-ingester/internal/config"
 	"time-value-analyser/multi-ingester/internal/model"
+	"time-value-analyser/multi-ingester/internal/store"
 	"time-value-analyser/multi-ingester/internal/util"
 )
 
 type lokiSink struct {
 	cfg    config.LokiConfig
 	client *http.Client
+	dedup  store.Dedup // optional; nil disables the pre-enqueue dedup check
 }
 
-func NewLoki(cfg config.LokiConfig) Sink {
+// NewLoki builds the Loki sink. dedup may be nil; when set, events already
+// marked seen (e.g. by an upstream source dedup check) are dropped before
+// being enqueued, so a restart mid-batch doesn't re-push them.
+func NewLoki(cfg config.LokiConfig, dedup store.Dedup) Sink {
 	to := cfg.Timeout
 	if to == 0 {
 		to = 10 * time.Second
 	}
-	return &lokiSink{cfg: cfg, client: util.NewHTTPClient(to)}
+	return &lokiSink{cfg: cfg, client: util.NewHTTPClient(to), dedup: dedup}
 }
 
 func (l *lokiSink) Name() string { return "loki" }
 
-func (l *lokiSink) Push(ctx context.Context, events []model.Event) error {
-	if len(events) == 0 {
-		return nil
-	}
+// lokiBatch groups events into streams by identical label sets, the shape
+// both the JSON and protobuf Loki push transports build from.
+type lokiBatch struct {
+	streams map[string]*lokiStream
+}
 
-	type stream struct {
-		Stream map[string]string `json:"stream"`
-		Values [][2]string       `json:"values"`
-	}
-	payload := struct {
-		Streams []stream `json:"streams"`
-	}{}
+type lokiStream struct {
+	labels  map[string]string
+	entries []lokiEntry
+}
+
+type lokiEntry struct {
+	ts   time.Time
+	line string
+}
+
+func buildBatch(events []model.Event) *lokiBatch {
+	b := &lokiBatch{streams: make(map[string]*lokiStream)}
+	seenLines := make(map[string]map[string]bool) // streamKey -> "ts|line" -> seen
 	for _, e := range events {
+		lbls := map[string]string{"source": e.Source}
+		for k, v := range e.Labels {
+			lbls[k] = v
+		}
+		key := labelSetKey(lbls)
 		line, _ := json.Marshal(map[string]any{
 			"id":        e.ID,
 			"title":     e.Title,
@@ -50,44 +75,227 @@ func (l *lokiSink) Push(ctx context.Context, events []model.Event) error {
 			"published": e.Published.Format(time.RFC3339),
 			"source":    e.Source,
 		})
-		// line: compact JSON (Raw + selected fields)
-		lbls := map[string]string{
-			"source": e.Source,
+
+		st, ok := b.streams[key]
+		if !ok {
+			st = &lokiStream{labels: lbls}
+			b.streams[key] = st
+			seenLines[key] = make(map[string]bool)
 		}
-		for k, v := range e.Labels {
-			lbls[k] = v
+
+		dedupKey := strconv.FormatInt(e.Published.UnixNano(), 10) + "|" + string(line)
+		if seenLines[key][dedupKey] {
+			continue
 		}
+		seenLines[key][dedupKey] = true
+		st.entries = append(st.entries, lokiEntry{ts: e.Published, line: string(line)})
+	}
+	for _, st := range b.streams {
+		sort.Slice(st.entries, func(i, j int) bool { return st.entries[i].ts.Before(st.entries[j].ts) })
+	}
+	return b
+}
 
-		// Loki expects ns timestamp as a decimal string
-		ts := e.Published.UnixNano()
-		payload.Streams = append(payload.Streams, stream{
-			Stream: lbls,
-			Values: [][2]string{
-				{fmt.Sprintf("%d", ts), string(line)},
-			},
-		})
+func labelSetKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// formatLabels renders a label set in logql stream-selector form, e.g. `{source="gta"}`.
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", k, labels[k])
 	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+type jsonStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type jsonPayload struct {
+	Streams []jsonStream `json:"streams"`
+}
 
+func (b *lokiBatch) toJSON() []byte {
+	payload := jsonPayload{}
+	for _, st := range b.streams {
+		values := make([][2]string, 0, len(st.entries))
+		for _, en := range st.entries {
+			values = append(values, [2]string{strconv.FormatInt(en.ts.UnixNano(), 10), en.line})
+		}
+		payload.Streams = append(payload.Streams, jsonStream{Stream: st.labels, Values: values})
+	}
 	body, _ := json.Marshal(payload)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.cfg.URL+"/loki/api/v1/push", bytes.NewReader(body))
-	if err != nil {
-		return err
+	return body
+}
+
+func (b *lokiBatch) toProto() *push.PushRequest {
+	req := &push.PushRequest{}
+	for _, st := range b.streams {
+		entries := make([]push.Entry, 0, len(st.entries))
+		for _, en := range st.entries {
+			entries = append(entries, push.Entry{Timestamp: en.ts, Line: en.line})
+		}
+		req.Streams = append(req.Streams, push.Stream{Labels: formatLabels(st.labels), Entries: entries})
 	}
-	req.Header.Set("Content-Type", "application/json")
-	if l.cfg.TenantID != "" {
-		req.Header.Set("X-Scope-OrgID", l.cfg.TenantID)
+	return req
+}
+
+// splitBatch partitions events into sub-batches that respect MaxBatchEntries/MaxBatchBytes,
+// so a single oversized fetch doesn't blow past Loki's max_line_size / rate limits.
+func splitBatch(events []model.Event, maxEntries, maxBytes int) [][]model.Event {
+	if maxEntries <= 0 && maxBytes <= 0 {
+		return [][]model.Event{events}
 	}
-	if ua := l.cfg.UserAgent; ua != "" {
-		req.Header.Set("User-Agent", ua)
+	var out [][]model.Event
+	var cur []model.Event
+	curBytes := 0
+	for _, e := range events {
+		lineBytes := len(e.Title) + len(e.Summary) + len(e.URL) + 64
+		if len(cur) > 0 && ((maxEntries > 0 && len(cur) >= maxEntries) || (maxBytes > 0 && curBytes+lineBytes > maxBytes)) {
+			out = append(out, cur)
+			cur = nil
+			curBytes = 0
+		}
+		cur = append(cur, e)
+		curBytes += lineBytes
 	}
-	resp, err := l.client.Do(req)
-	if err != nil {
-		return err
+	if len(cur) > 0 {
+		out = append(out, cur)
 	}
+	return out
+}
 
-	defer resp.Body.Close()
-	if resp.StatusCode/100 != 2 {
-		return fmt.Errorf("loki push failed http %d", resp.StatusCode)
+func (l *lokiSink) Push(ctx context.Context, events []model.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if l.dedup != nil {
+		filtered := make([]model.Event, 0, len(events))
+		for _, e := range events {
+			key := e.Source + "::" + e.ID
+			if l.dedup.Seen(key) {
+				continue
+			}
+			filtered = append(filtered, e)
+		}
+		events = filtered
+		if len(events) == 0 {
+			return nil
+		}
+	}
+	for _, chunk := range splitBatch(events, l.cfg.MaxBatchEntries, l.cfg.MaxBatchBytes) {
+		if err := l.pushChunk(ctx, chunk); err != nil {
+			return err
+		}
+		// Only mark a chunk's keys as seen once Loki has actually accepted
+		// it -- pushChunk already retried through transient failures, so an
+		// error here means retries are exhausted or ctx was cancelled. Mark
+		// them any earlier and an event that never made it to Loki would
+		// never be retried on the next Push either, silently dropping it.
+		if l.dedup != nil {
+			for _, e := range chunk {
+				l.dedup.Mark(e.Source + "::" + e.ID)
+			}
+		}
 	}
 	return nil
 }
+
+func (l *lokiSink) pushChunk(ctx context.Context, events []model.Event) error {
+	batch := buildBatch(events)
+
+	var body []byte
+	var contentType, contentEncoding string
+	switch l.cfg.Encoding {
+	case "protobuf-snappy":
+		raw, err := batch.toProto().Marshal()
+		if err != nil {
+			return fmt.Errorf("loki: marshal protobuf: %w", err)
+		}
+		body = snappy.Encode(nil, raw)
+		contentType = "application/x-protobuf"
+		contentEncoding = "snappy"
+	case "json-gzip":
+		raw := batch.toJSON()
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		if _, err := gw.Write(raw); err != nil {
+			return fmt.Errorf("loki: gzip: %w", err)
+		}
+		if err := gw.Close(); err != nil {
+			return fmt.Errorf("loki: gzip: %w", err)
+		}
+		body = buf.Bytes()
+		contentType = "application/json"
+		contentEncoding = "gzip"
+	default:
+		body = batch.toJSON()
+		contentType = "application/json"
+	}
+
+	return util.Retry(ctx, l.Name(), max(1, l.cfg.MaxRetries), defaultDur(l.cfg.Backoff, 500*time.Millisecond), defaultDur(l.cfg.MaxBackoff, 5*time.Second), nil, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.cfg.URL+"/loki/api/v1/push", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", contentType)
+		if contentEncoding != "" {
+			req.Header.Set("Content-Encoding", contentEncoding)
+		}
+		if l.cfg.TenantID != "" {
+			req.Header.Set("X-Scope-OrgID", l.cfg.TenantID)
+		}
+		if ua := l.cfg.UserAgent; ua != "" {
+			req.Header.Set("User-Agent", ua)
+		}
+		resp, err := l.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			return &util.HTTPStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(b))}
+		}
+		return nil
+	})
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func defaultDur(v, def time.Duration) time.Duration {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,190 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"time-value-analyser/multi-ingester/internal/config"
+	"time-value-analyser/multi-ingester/internal/model"
+)
+
+// memDedup is a minimal store.Dedup fake that records every Mark call, so
+// tests can assert on exactly which keys got marked seen.
+type memDedup struct {
+	seen   map[string]bool
+	marked []string
+}
+
+func newMemDedup() *memDedup { return &memDedup{seen: make(map[string]bool)} }
+
+func (d *memDedup) Seen(key string) bool { return d.seen[key] }
+func (d *memDedup) Mark(key string) {
+	d.seen[key] = true
+	d.marked = append(d.marked, key)
+}
+func (d *memDedup) Close() error { return nil }
+
+func TestLokiPush_DoesNotMarkEventsOnFailedPush(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	d := newMemDedup()
+	s := NewLoki(config.LokiConfig{URL: srv.URL, MaxRetries: 1}, d)
+
+	events := []model.Event{{Source: "gta", ID: "1", Title: "t"}}
+	if err := s.Push(context.Background(), events); err == nil {
+		t.Fatal("expected Push to fail when Loki returns 500")
+	}
+	if d.Seen("gta::1") {
+		t.Fatal("event was marked seen even though the push never succeeded -- it would never be retried")
+	}
+}
+
+func TestLokiPush_MarksEventsOnlyAfterSuccessfulPush(t *testing.T) {
+	var gotBody bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	d := newMemDedup()
+	s := NewLoki(config.LokiConfig{URL: srv.URL, MaxRetries: 1}, d)
+
+	events := []model.Event{{Source: "gta", ID: "1", Title: "t", Published: time.Now()}}
+	if err := s.Push(context.Background(), events); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !gotBody {
+		t.Fatal("server never received the push")
+	}
+	if !d.Seen("gta::1") {
+		t.Fatal("event should be marked seen after a successful push")
+	}
+}
+
+func TestBuildBatch_GroupsByLabelSetAndDedupesSameTimestampLine(t *testing.T) {
+	now := time.Now()
+	events := []model.Event{
+		{Source: "gta", ID: "1", Title: "a", Published: now, Labels: map[string]string{"country": "us"}},
+		{Source: "gta", ID: "2", Title: "b", Published: now.Add(time.Second), Labels: map[string]string{"country": "de"}},
+		{Source: "gta", ID: "1", Title: "a", Published: now, Labels: map[string]string{"country": "us"}}, // exact duplicate
+	}
+
+	b := buildBatch(events)
+	if len(b.streams) != 2 {
+		t.Fatalf("expected 2 streams (one per distinct label set), got %d", len(b.streams))
+	}
+
+	var usStream *lokiStream
+	for _, st := range b.streams {
+		if st.labels["country"] == "us" {
+			usStream = st
+		}
+	}
+	if usStream == nil {
+		t.Fatal("expected a stream labeled country=us")
+	}
+	if len(usStream.entries) != 1 {
+		t.Fatalf("expected the duplicate ts+line entry to be deduped, got %d entries", len(usStream.entries))
+	}
+}
+
+func TestBuildBatch_SortsEntriesWithinAStreamByTimestamp(t *testing.T) {
+	now := time.Now()
+	events := []model.Event{
+		{Source: "gta", ID: "2", Title: "second", Published: now.Add(time.Minute)},
+		{Source: "gta", ID: "1", Title: "first", Published: now},
+	}
+	b := buildBatch(events)
+	st := b.streams[labelSetKey(map[string]string{"source": "gta"})]
+	if len(st.entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(st.entries))
+	}
+	if !st.entries[0].ts.Equal(now) || !st.entries[1].ts.Equal(now.Add(time.Minute)) {
+		t.Fatal("expected entries sorted oldest first")
+	}
+}
+
+func TestBuildBatch_ToJSONAndToProtoRoundTripTheSameEntries(t *testing.T) {
+	now := time.Now()
+	events := []model.Event{
+		{Source: "gta", ID: "1", Title: "t", URL: "http://x", Published: now},
+	}
+	b := buildBatch(events)
+
+	var payload jsonPayload
+	if err := json.Unmarshal(b.toJSON(), &payload); err != nil {
+		t.Fatalf("toJSON produced invalid JSON: %v", err)
+	}
+	if len(payload.Streams) != 1 || len(payload.Streams[0].Values) != 1 {
+		t.Fatalf("expected 1 stream with 1 value, got %+v", payload)
+	}
+	if payload.Streams[0].Stream["source"] != "gta" {
+		t.Fatalf("expected stream labels to include source=gta, got %v", payload.Streams[0].Stream)
+	}
+
+	req := b.toProto()
+	if len(req.Streams) != 1 || len(req.Streams[0].Entries) != 1 {
+		t.Fatalf("expected 1 proto stream with 1 entry, got %+v", req.Streams)
+	}
+	if req.Streams[0].Labels != `{source="gta"}` {
+		t.Fatalf("expected proto stream labels %q, got %q", `{source="gta"}`, req.Streams[0].Labels)
+	}
+	if req.Streams[0].Entries[0].Line != payload.Streams[0].Values[0][1] {
+		t.Fatal("expected toProto and toJSON to carry the same line content")
+	}
+}
+
+func TestSplitBatch_RespectsMaxEntries(t *testing.T) {
+	events := make([]model.Event, 5)
+	for i := range events {
+		events[i] = model.Event{Source: "gta", ID: string(rune('a' + i))}
+	}
+	chunks := splitBatch(events, 2, 0)
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks of at most 2 events each, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if len(c) > 2 {
+			t.Fatalf("chunk %d has %d events, want at most 2", i, len(c))
+		}
+	}
+	var total int
+	for _, c := range chunks {
+		total += len(c)
+	}
+	if total != len(events) {
+		t.Fatalf("expected all %d events preserved across chunks, got %d", len(events), total)
+	}
+}
+
+func TestSplitBatch_RespectsMaxBytes(t *testing.T) {
+	big := model.Event{Source: "gta", ID: "1", Title: string(make([]byte, 200))}
+	events := []model.Event{big, big, big}
+	chunks := splitBatch(events, 0, 300)
+	if len(chunks) < 2 {
+		t.Fatalf("expected events to split across multiple chunks to respect the byte limit, got %d chunk(s)", len(chunks))
+	}
+	var total int
+	for _, c := range chunks {
+		total += len(c)
+	}
+	if total != len(events) {
+		t.Fatalf("expected all %d events preserved across chunks, got %d", len(events), total)
+	}
+}
+
+func TestSplitBatch_NoLimitsReturnsSingleChunk(t *testing.T) {
+	events := []model.Event{{Source: "gta", ID: "1"}, {Source: "gta", ID: "2"}}
+	chunks := splitBatch(events, 0, 0)
+	if len(chunks) != 1 || len(chunks[0]) != 2 {
+		t.Fatalf("expected a single chunk with both events, got %+v", chunks)
+	}
+}
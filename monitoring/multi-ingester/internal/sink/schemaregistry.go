@@ -0,0 +1,70 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"time-value-analyser/multi-ingester/internal/config"
+	"time-value-analyser/multi-ingester/internal/util"
+)
+
+// schemaRegistryClient resolves a Confluent Schema Registry subject to its
+// latest schema id, caching the result per subject for the process lifetime.
+type schemaRegistryClient struct {
+	cfg    config.SchemaRegistryConfig
+	client *http.Client
+
+	mu  sync.Mutex
+	ids map[string]int32
+}
+
+func newSchemaRegistryClient(cfg config.SchemaRegistryConfig) *schemaRegistryClient {
+	return &schemaRegistryClient{cfg: cfg, client: util.NewHTTPClient(5 * time.Second), ids: make(map[string]int32)}
+}
+
+func (c *schemaRegistryClient) idForSubject(topic string) (int32, error) {
+	subject := c.cfg.Subject
+	if subject == "" {
+		subject = topic + "-value"
+	}
+
+	c.mu.Lock()
+	if id, ok := c.ids[subject]; ok {
+		c.mu.Unlock()
+		return id, nil
+	}
+	c.mu.Unlock()
+
+	url := fmt.Sprintf("%s/subjects/%s/versions/latest", strings.TrimRight(c.cfg.URL, "/"), subject)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if c.cfg.Username != "" {
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("schema registry: %s: http %d", subject, resp.StatusCode)
+	}
+
+	var out struct {
+		ID int32 `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	c.ids[subject] = out.ID
+	c.mu.Unlock()
+	return out.ID, nil
+}
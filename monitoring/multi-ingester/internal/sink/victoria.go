@@ -3,141 +3,274 @@ package sink
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"time-value-analyser/multi-ingester/internal/config"
+	"time-value-analyser/multi-ingester/internal/metrics"
 	"time-value-analyser/multi-ingester/internal/model"
 	"time-value-analyser/multi-ingester/internal/util"
+	"time-value-analyser/multi-ingester/internal/vminsert"
 )
 
 type victoriaSink struct {
-	cfg    config.VictoriaConfig
-	client *http.Client
+	cfg config.VictoriaConfig
+	vm  *vminsert.Client
+
+	// walSeq gives WAL filenames a monotonically increasing suffix so
+	// concurrent Push calls never collide and the flusher drains them in
+	// acceptance order.
+	walSeq uint64
+	queued int64 // undelivered WAL files on disk; bounds Push when WALDir is set
 }
 
+// NewVictoria builds the VictoriaMetrics sink. When cfg.WALDir is set, Push
+// persists batches to disk and a background goroutine flushes them
+// asynchronously; otherwise Push sends synchronously, one request per chunk.
 func NewVictoria(cfg config.VictoriaConfig) (Sink, error) {
 	to := cfg.Timeout
 	if to == 0 {
 		to = 10 * time.Second
 	}
-	return &victoriaSink{
-		cfg:    cfg,
-		client: util.NewHTTPClient(to),
-	}, nil
+	v := &victoriaSink{
+		cfg: cfg,
+		vm:  vminsert.NewClient(cfg.URL, cfg.UserAgent, util.NewHTTPClient(to)),
+	}
+	if cfg.WALDir != "" {
+		if err := os.MkdirAll(cfg.WALDir, 0755); err != nil {
+			return nil, fmt.Errorf("victoria: create wal dir: %w", err)
+		}
+		existing, err := v.walFiles()
+		if err != nil {
+			return nil, fmt.Errorf("victoria: read wal dir: %w", err)
+		}
+		atomic.StoreInt64(&v.queued, int64(len(existing)))
+		go v.flushLoop()
+	}
+	return v, nil
 }
 
 func (v *victoriaSink) Name() string { return "victoria" }
 
+// Push sends events to VictoriaMetrics. With WALDir unset, it encodes and
+// POSTs each chunk synchronously. With WALDir set, each chunk is persisted
+// to disk and Push returns once it's durably queued; the background
+// flusher delivers it.
 func (v *victoriaSink) Push(ctx context.Context, events []model.Event) error {
 	if len(events) == 0 {
 		return nil
 	}
-
-	var buf bytes.Buffer
-	for _, e := range events {
-		ts := e.Published.Unix()
-		metricName := "event_count_total"
-		lbls := fmt.Sprintf(`source="%s"`, e.Source)
-		for k, val := range e.Labels {
-			lbls += fmt.Sprintf(`,%s="%s"`, k, val)
+	for _, chunk := range splitEventBatch(events, v.cfg.MaxBatchEntries, v.cfg.MaxBatchBytes) {
+		if v.cfg.WALDir == "" {
+			if err := v.send(ctx, chunk); err != nil {
+				return err
+			}
+			continue
 		}
-		line := fmt.Sprintf(`%s{%s} 1 %d\n`, metricName, lbls, ts*1000)
-		buf.WriteString(line)
+		if err := v.enqueue(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue persists chunk as a new WAL file, rejecting it once
+// MaxQueuedFiles undelivered files have already accumulated (backpressure:
+// the caller's dedup/state won't advance, so the next cycle retries).
+func (v *victoriaSink) enqueue(chunk []model.Event) error {
+	maxQueued := v.cfg.MaxQueuedFiles
+	if maxQueued <= 0 {
+		maxQueued = 1000
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.cfg.URL+"/api/v1/import/prometheus", &buf)
+	if atomic.LoadInt64(&v.queued) >= int64(maxQueued) {
+		return fmt.Errorf("victoria: wal queue full (%d files), dropping push", maxQueued)
+	}
+	b, err := json.Marshal(chunk)
 	if err != nil {
-		return err
+		return fmt.Errorf("victoria: marshal wal chunk: %w", err)
 	}
-	req.Header.Set("Content-Type", "text/plain")
-	if ua := v.cfg.UserAgent; ua != "" {
-		req.Header.Set("User-Agent", ua)
+	seq := atomic.AddUint64(&v.walSeq, 1)
+	name := fmt.Sprintf("%020d-%06d.json", time.Now().UnixNano(), seq)
+	if err := os.WriteFile(filepath.Join(v.cfg.WALDir, name), b, 0644); err != nil {
+		return fmt.Errorf("victoria: write wal file: %w", err)
 	}
+	atomic.AddInt64(&v.queued, 1)
+	return nil
+}
 
-	resp, err := v.client.Do(req)
+// flushLoop periodically drains WALDir, oldest files first, so events
+// written before a crash or restart are still delivered once the process
+// comes back up.
+func (v *victoriaSink) flushLoop() {
+	interval := defaultDur(v.cfg.FlushInterval, 5*time.Second)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		v.flushOnce()
+	}
+}
+
+func (v *victoriaSink) flushOnce() {
+	files, err := v.walFiles()
 	if err != nil {
-		return err
+		return
+	}
+	maxEntries := v.cfg.MaxBatchEntries
+	if maxEntries <= 0 {
+		maxEntries = 5000
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode/100 != 2 {
-		return fmt.Errorf("victoria push failed: %s", resp.Status)
+	var batch []model.Event
+	var consumed []string
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), defaultDur(v.cfg.Timeout, 10*time.Second))
+		defer cancel()
+		if err := v.send(ctx, batch); err != nil {
+			metrics.IncCounter("ingester_sink_wal_flush_total", map[string]string{"sink": "victoria", "status": "error"}, 1)
+			batch, consumed = nil, nil
+			return
+		}
+		for _, f := range consumed {
+			_ = os.Remove(f)
+		}
+		atomic.AddInt64(&v.queued, -int64(len(consumed)))
+		metrics.IncCounter("ingester_sink_wal_flush_total", map[string]string{"sink": "victoria", "status": "ok"}, float64(len(consumed)))
+		batch, consumed = nil, nil
 	}
-	return nil
+
+	for _, f := range files {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var chunk []model.Event
+		if err := json.Unmarshal(raw, &chunk); err != nil {
+			// Corrupt/partial WAL file; drop it rather than blocking the queue forever.
+			_ = os.Remove(f)
+			atomic.AddInt64(&v.queued, -1)
+			continue
+		}
+		if len(batch)+len(chunk) > maxEntries && len(batch) > 0 {
+			flush()
+		}
+		batch = append(batch, chunk...)
+		consumed = append(consumed, f)
+	}
+	flush()
 }
 
-// Emit simple counters per label group for this batch at ingest time.
-// metric names: news_events_count{source,language,env,...}
-/*
-func (v *Victoria) EmitCounts(ctx context.Context, metric string, baseLabels map[string]string, events []model.Event) error {
-	if v.url == "" || len(events) == 0 {
-		return nil
+// walFiles lists WALDir's files in acceptance order (the filename prefix is
+// a nanosecond timestamp + sequence number, so lexical sort is chronological).
+func (v *victoriaSink) walFiles() ([]string, error) {
+	entries, err := os.ReadDir(v.cfg.WALDir)
+	if err != nil {
+		return nil, err
 	}
-	ts := time.Now().UnixMilli()
-	type key struct{ k string }
-	groups := map[key]int{}
-	for _, ev := range events {
-		lbls := map[string]string{}
-		for k, v := range baseLabels {
-			lbls[k] = v
-		}
-		lbls["source"] = ev.Source
-		if ev.Lang != "" {
-			lbls["language"] = ev.Lang
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, filepath.Join(v.cfg.WALDir, e.Name()))
 		}
-		if ev.Country != "" {
-			lbls["country"] = ev.Country
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// splitEventBatch partitions events into sub-batches that respect
+// maxEntries/maxBytes, mirroring splitBatch in loki.go.
+func splitEventBatch(events []model.Event, maxEntries, maxBytes int) [][]model.Event {
+	if maxEntries <= 0 && maxBytes <= 0 {
+		return [][]model.Event{events}
+	}
+	var out [][]model.Event
+	var cur []model.Event
+	curBytes := 0
+	for _, e := range events {
+		evBytes := len(e.Title) + len(e.Summary) + len(e.URL) + 64
+		if len(cur) > 0 && ((maxEntries > 0 && len(cur) >= maxEntries) || (maxBytes > 0 && curBytes+evBytes > maxBytes)) {
+			out = append(out, cur)
+			cur = nil
+			curBytes = 0
 		}
-		for k, v := range ev.Labels {
-			lbls[k] = v
+		cur = append(cur, e)
+		curBytes += evBytes
+	}
+	if len(cur) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
+
+// send encodes and POSTs one chunk using the configured format, retrying
+// per v.cfg.MaxRetries/Backoff/MaxBackoff. The actual HTTP push is handled
+// by the vminsert client; this method only decides which wire format to
+// use and shapes events into it.
+func (v *victoriaSink) send(ctx context.Context, events []model.Event) error {
+	return util.Retry(ctx, v.Name(), max(1, v.cfg.MaxRetries), defaultDur(v.cfg.Backoff, 500*time.Millisecond), defaultDur(v.cfg.MaxBackoff, 5*time.Second), nil, func() error {
+		switch v.cfg.Format {
+		case "remote_write":
+			return v.vm.PushRemoteWrite(ctx, eventSamples(events))
+		default: // "text"
+			return v.vm.PushTextExposition(ctx, buildTextExposition(events))
 		}
-		// deterministic label ordering for cache friendliness
+	})
+}
+
+// buildTextExposition renders one `event_count_total{...} 1 <ms>` line per
+// event, terminated with an actual newline (the previous implementation
+// used a backtick string, so its "\n" was the two literal characters `\`
+// and `n` rather than a line break, and VictoriaMetrics silently dropped
+// every sample after the first).
+func buildTextExposition(events []model.Event) []byte {
+	var buf bytes.Buffer
+	for _, e := range events {
+		ts := e.Published.UnixMilli()
+		lbls := eventLabels(e)
 		keys := make([]string, 0, len(lbls))
 		for k := range lbls {
 			keys = append(keys, k)
 		}
 		sort.Strings(keys)
-		b := bytes.Buffer{}
+		var lb strings.Builder
 		for i, k := range keys {
 			if i > 0 {
-				b.WriteByte(',')
+				lb.WriteByte(',')
 			}
-			fmt.Fprintf(&b, "%s=\"%s\"", k, escape(lbls[k]))
+			fmt.Fprintf(&lb, "%s=%q", k, lbls[k])
 		}
-		groups[key{"{" + b.String() + "}"}]++
-	}
-	// build Prom text
-	buf := &bytes.Buffer{}
-	for k, n := range groups {
-		fmt.Fprintf(buf, "%s%s %d %d\n", metric, k.k, n, ts)
+		fmt.Fprintf(&buf, "event_count_total{%s} 1 %d\n", lb.String(), ts)
 	}
-	// POST
-	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, v.url+"/api/v1/import/prometheus", bytes.NewReader(buf.Bytes()))
-	req.Header.Set("Content-Type", "text/plain")
-	resp, err := v.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode/100 != 2 {
-		return fmt.Errorf("victoria http %d", resp.StatusCode)
+	return buf.Bytes()
+}
+
+// eventSamples converts events into vminsert.Samples for the remote_write
+// format, one single-sample timeseries per event.
+func eventSamples(events []model.Event) []vminsert.Sample {
+	samples := make([]vminsert.Sample, 0, len(events))
+	for _, e := range events {
+		samples = append(samples, vminsert.Sample{
+			Name:      "event_count_total",
+			Labels:    eventLabels(e),
+			Value:     1,
+			Timestamp: e.Published,
+		})
 	}
-	return nil
+	return samples
 }
 
-func escape(s string) string {
-	// minimal escape for label values
-	res := make([]rune, 0, len(s))
-	for _, r := range s {
-		if r == '"' {
-			res = append(res, '\\', '"')
-		} else if r == '\\' {
-			res = append(res, '\\', '\\')
-		} else {
-			res = append(res, r)
-		}
+func eventLabels(e model.Event) map[string]string {
+	lbls := map[string]string{"source": e.Source}
+	for k, val := range e.Labels {
+		lbls[k] = val
 	}
-	return string(res)
+	return lbls
 }
-*/
@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
 	"time-value-analyser/multi-ingester/internal/config"
+	"time-value-analyser/multi-ingester/internal/metrics"
 	"time-value-analyser/multi-ingester/internal/model"
 	"time-value-analyser/multi-ingester/internal/store"
 	"time-value-analyser/multi-ingester/internal/util"
@@ -30,6 +32,10 @@ func NewCoinDeskSource(cfg config.CoinDeskConfig) *coinDeskSource {
 }
 
 func (s *coinDeskSource) Name() string { return "coindesk" }
+
+// StatePath satisfies StateFileAware so the scheduler can seed
+// ingester_source_last_success_timestamp_seconds from this file's mtime.
+func (s *coinDeskSource) StatePath() string { return s.cfg.StatePath }
 func (s *coinDeskSource) Fetch(ctx context.Context) ([]model.Event, error) {
 	base := strings.TrimRight(s.cfg.BaseURL, "/")
 	if base == "" {
@@ -122,7 +128,7 @@ func (s *coinDeskSource) Fetch(ctx context.Context) ([]model.Event, error) {
 			fmt.Printf("coindesk: GET %s\n", u.String())
 
 			var resp *http.Response
-			err = util.Retry(ctx, max(1, s.cfg.MaxRetries), defaultDur(s.cfg.Backoff, 500*time.Millisecond), defaultDur(s.cfg.MaxBackoff, 5*time.Second), func() error {
+			err = util.Retry(ctx, s.Name(), max(1, s.cfg.MaxRetries), defaultDur(s.cfg.Backoff, 500*time.Millisecond), defaultDur(s.cfg.MaxBackoff, 5*time.Second), nil, func() error {
 				r, err := s.client.Do(req)
 				if err != nil {
 					return err
@@ -130,7 +136,7 @@ func (s *coinDeskSource) Fetch(ctx context.Context) ([]model.Event, error) {
 				if r.StatusCode/100 != 2 {
 					b, _ := io.ReadAll(io.LimitReader(r.Body, 1024))
 					r.Body.Close()
-					return fmt.Errorf("coindesk %d: %s", r.StatusCode, strings.TrimSpace(string(b)))
+					return &util.HTTPStatusError{StatusCode: r.StatusCode, Body: strings.TrimSpace(string(b))}
 				}
 				resp = r
 				return nil
@@ -210,6 +216,7 @@ func (s *coinDeskSource) Fetch(ctx context.Context) ([]model.Event, error) {
 				}
 			}
 			fmt.Printf("coindesk: parsed rows page=%d endpoint=%s -> %d\n", page, endpoint, len(flat))
+			metrics.IncCounter("ingester_source_rows_parsed_total", map[string]string{"source": "coindesk", "endpoint": endpoint}, float64(len(flat)))
 			if len(flat) == 0 {
 				if page == 1 { /* try next endpoint */
 				}
@@ -218,88 +225,14 @@ func (s *coinDeskSource) Fetch(ctx context.Context) ([]model.Event, error) {
 
 			// Map to events — handle UPPERCASE (Data API) and lowercase (generic)
 			for i, m := range flat {
-				// ID: may be numeric
-				id := pickStr(m, "id", "uuid", "_id", "ID")
-				if id == "" {
-					if v, ok := m["ID"]; ok {
-						id = fmt.Sprint(v)
-					}
-				}
-
-				title := pickStr(m, "title", "headline", "name", "TITLE")
-				urlstr := pickStr(m, "url", "link", "permalink", "URL", "GUID")
-				summary := pickStr(m, "summary", "dek", "excerpt", "description", "SUBTITLE")
-
-				// published time
-				var ts time.Time
-				if s := pickStr(m, "published_at", "publish_date", "published_on", "date_published", "time_published"); s != "" {
-					if t, err := parseTimeFlexible(s); err == nil {
-						ts = t
-					}
-				}
-				if ts.IsZero() {
-					// CoinDesk Data API: epoch seconds in PUBLISHED_ON
-					if v, ok := m["PUBLISHED_ON"]; ok {
-						switch vv := v.(type) {
-						case float64:
-							ts = time.Unix(int64(vv), 0).UTC()
-						case int64:
-							ts = time.Unix(vv, 0).UTC()
-						case json.Number:
-							if sec, err := vv.Int64(); err == nil {
-								ts = time.Unix(sec, 0).UTC()
-							}
-						}
-					}
-				}
-				if ts.IsZero() {
-					ts = now
-				}
-				if ts.After(latest) {
-					latest = ts
-				}
-
-				labels := map[string]string{"news_source": "coindesk"}
-				if cat := pickStr(m, "category", "section", "TYPE"); cat != "" {
-					labels["category"] = cat
-				}
-				if s.cfg.Language != "" {
-					labels["language"] = s.cfg.Language
-				}
-
-				// tags (optional)
-				if tv, ok := m["tags"]; ok {
-					switch t := tv.(type) {
-					case []any:
-						parts := make([]string, 0, len(t))
-						for _, it := range t {
-							parts = append(parts, fmt.Sprint(it))
-						}
-						if len(parts) > 0 {
-							labels["tags"] = strings.Join(parts, ",")
-						}
-					case string:
-						if t != "" {
-							labels["tags"] = t
-						}
-					}
+				ev := s.mapEvent(m, now)
+				if ev.Published.After(latest) {
+					latest = ev.Published
 				}
-
-				all = append(all, model.Event{
-					ID:        id,
-					Source:    s.Name(),
-					Title:     title,
-					Summary:   summary,
-					URL:       urlstr,
-					Published: ts,
-					Lang:      s.cfg.Language,
-					Country:   "",
-					Raw:       m,
-					Labels:    labels,
-				})
+				all = append(all, ev)
 
 				if i == 0 {
-					fmt.Printf("coindesk: first mapped: id=%s ts=%s\n", id, ts.Format(time.RFC3339))
+					fmt.Printf("coindesk: first mapped: id=%s ts=%s\n", ev.ID, ev.Published.Format(time.RFC3339))
 				}
 			}
 			// advance page; if fewer than pageSize, stop
@@ -319,3 +252,184 @@ func (s *coinDeskSource) Fetch(ctx context.Context) ([]model.Event, error) {
 
 	return all, nil
 }
+
+// mapEvent converts one raw article row into a model.Event, handling both
+// the UPPERCASE CoinDesk Data API shape and the lowercase generic shape.
+// now is used as the published-time fallback when a row carries none.
+func (s *coinDeskSource) mapEvent(m map[string]any, now time.Time) model.Event {
+	id := pickStr(m, "id", "uuid", "_id", "ID")
+	if id == "" {
+		if v, ok := m["ID"]; ok {
+			id = fmt.Sprint(v)
+		}
+	}
+
+	title := pickStr(m, "title", "headline", "name", "TITLE")
+	urlstr := pickStr(m, "url", "link", "permalink", "URL", "GUID")
+	summary := pickStr(m, "summary", "dek", "excerpt", "description", "SUBTITLE")
+
+	var ts time.Time
+	if str := pickStr(m, "published_at", "publish_date", "published_on", "date_published", "time_published"); str != "" {
+		if t, err := parseTimeFlexible(str); err == nil {
+			ts = t
+		}
+	}
+	if ts.IsZero() {
+		// CoinDesk Data API: epoch seconds in PUBLISHED_ON
+		if v, ok := m["PUBLISHED_ON"]; ok {
+			switch vv := v.(type) {
+			case float64:
+				ts = time.Unix(int64(vv), 0).UTC()
+			case int64:
+				ts = time.Unix(vv, 0).UTC()
+			case json.Number:
+				if sec, err := vv.Int64(); err == nil {
+					ts = time.Unix(sec, 0).UTC()
+				}
+			}
+		}
+	}
+	if ts.IsZero() {
+		ts = now
+	}
+
+	labels := map[string]string{"news_source": "coindesk"}
+	if cat := pickStr(m, "category", "section", "TYPE"); cat != "" {
+		labels["category"] = cat
+	}
+	if s.cfg.Language != "" {
+		labels["language"] = s.cfg.Language
+	}
+	if tv, ok := m["tags"]; ok {
+		switch t := tv.(type) {
+		case []any:
+			parts := make([]string, 0, len(t))
+			for _, it := range t {
+				parts = append(parts, fmt.Sprint(it))
+			}
+			if len(parts) > 0 {
+				labels["tags"] = strings.Join(parts, ",")
+			}
+		case string:
+			if t != "" {
+				labels["tags"] = t
+			}
+		}
+	}
+
+	return model.Event{
+		ID:        id,
+		Source:    s.Name(),
+		Title:     title,
+		Summary:   summary,
+		URL:       urlstr,
+		Published: ts,
+		Lang:      s.cfg.Language,
+		Country:   "",
+		Raw:       m,
+		Labels:    labels,
+	}
+}
+
+// Stream satisfies Streamer. It backfills with Fetch from the persisted
+// cursor up to now, then subscribes to cfg.StreamURL for live updates,
+// deduping against a bounded LRU of recently-seen event IDs and
+// checkpointing LastPublished every PersistEvery events or
+// PersistInterval, whichever comes first. If the feed never accepts a
+// websocket connection, it falls back to polling Fetch instead.
+func (s *coinDeskSource) Stream(ctx context.Context, out chan<- model.Event) error {
+	if s.cfg.StreamURL == "" {
+		return fmt.Errorf("coindesk: stream_url not configured")
+	}
+
+	backfill, err := s.Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("coindesk: stream backfill: %w", err)
+	}
+	for _, e := range backfill {
+		select {
+		case out <- e:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	dedup, err := store.NewDedupFromConfig(config.DedupConfig{
+		Backend: "memory",
+		MaxKeys: s.cfg.DedupWindow,
+		TTL:     24 * time.Hour,
+	})
+	if err != nil {
+		return fmt.Errorf("coindesk: stream dedup: %w", err)
+	}
+	defer dedup.Close()
+
+	conn, err := dialWithRetry(ctx, s.Name(), s.cfg.StreamURL, max(1, s.cfg.MaxRetries), defaultDur(s.cfg.Backoff, 500*time.Millisecond), defaultDur(s.cfg.MaxBackoff, 30*time.Second))
+	if err != nil {
+		return pollFallback(ctx, s.Name(), 30*time.Second, s.Fetch, out)
+	}
+	defer conn.Close()
+
+	persistEvery := s.cfg.PersistEvery
+	if persistEvery <= 0 {
+		persistEvery = 50
+	}
+	persistInterval := defaultDur(s.cfg.PersistInterval, 10*time.Second)
+
+	var latest time.Time
+	sinceEvents := 0
+	lastPersist := time.Now()
+	checkpoint := func() {
+		if s.cfg.StatePath == "" || latest.IsZero() {
+			return
+		}
+		if err := store.SaveNewsState(s.cfg.StatePath, store.NewsState{LastPublished: latest.Format(time.RFC3339)}); err != nil {
+			log.Printf("coindesk: stream: save state: %v", err)
+		}
+		sinceEvents = 0
+		lastPersist = time.Now()
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			checkpoint()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("coindesk: stream read: %w", err)
+		}
+
+		var m map[string]any
+		if err := json.Unmarshal(raw, &m); err != nil {
+			continue
+		}
+		ev := s.mapEvent(m, time.Now().UTC())
+		if ev.ID != "" {
+			key := s.Name() + "::" + ev.ID
+			if dedup.Seen(key) {
+				continue
+			}
+			dedup.Mark(key)
+		}
+		if ev.Published.After(latest) {
+			latest = ev.Published
+		}
+
+		select {
+		case out <- ev:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		sinceEvents++
+		if sinceEvents >= persistEvery || time.Since(lastPersist) >= persistInterval {
+			checkpoint()
+		}
+	}
+}
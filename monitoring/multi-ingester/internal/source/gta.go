@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -21,18 +22,24 @@ import (
 type gtaSource struct {
 	cfg    config.GTAConfig
 	client *http.Client
+	dedup  store.Dedup    // optional; nil disables the pre-append dedup check
+	now    func() time.Time // overridden by tests so recorded fixtures stay deterministic
 }
 
-func NewGTASource(cfg config.GTAConfig) *gtaSource {
+func NewGTASource(cfg config.GTAConfig, dedup store.Dedup) *gtaSource {
 	to := cfg.HTTP.Timeout
 	if to == 0 {
 		to = 15 * time.Second
 	}
-	return &gtaSource{cfg: cfg, client: util.NewHTTPClient(to)}
+	return &gtaSource{cfg: cfg, client: util.NewHTTPClient(to), dedup: dedup, now: time.Now}
 }
 
 func (g *gtaSource) Name() string { return "gta" }
 
+// StatePath satisfies StateFileAware so the scheduler can seed
+// ingester_source_last_success_timestamp_seconds from this file's mtime.
+func (g *gtaSource) StatePath() string { return g.cfg.StatePath }
+
 // Fetch retrieves events from the GTA API within the configured time window.
 func (g *gtaSource) Fetch(ctx context.Context) ([]model.Event, error) {
 	base := strings.TrimRight(g.cfg.BaseURL, "/")
@@ -45,17 +52,17 @@ func (g *gtaSource) Fetch(ctx context.Context) ([]model.Event, error) {
 	// Build time window
 	var from, toStr string
 	if g.cfg.Window > 0 {
-		from = time.Now().UTC().Add(-g.cfg.Window).Format("2006-01-02")
-		toStr = time.Now().UTC().Format("2006-01-02")
+		from = g.now().UTC().Add(-g.cfg.Window).Format("2006-01-02")
+		toStr = g.now().UTC().Format("2006-01-02")
 	} else if st, err := store.LoadGTAState(g.cfg.StatePath); err == nil && st.LastAnnounced != "" {
 		from = st.LastAnnounced
-		toStr = time.Now().UTC().Format("2006-01-02")
+		toStr = g.now().UTC().Format("2006-01-02")
 	} else if g.cfg.Since != "" {
 		from = g.cfg.Since
-		toStr = time.Now().UTC().Format("2006-01-02")
+		toStr = g.now().UTC().Format("2006-01-02")
 	} else {
-		from = time.Now().UTC().Add(-24 * time.Hour).Format("2006-01-02")
-		toStr = time.Now().UTC().Format("2006-01-02")
+		from = g.now().UTC().Add(-24 * time.Hour).Format("2006-01-02")
+		toStr = g.now().UTC().Format("2006-01-02")
 	}
 
 	// GTA uses different date filters; try primary first, then fallbacks
@@ -80,7 +87,6 @@ func (g *gtaSource) Fetch(ctx context.Context) ([]model.Event, error) {
 
 	for {
 		var flat []map[string]any
-		usedFilter := ""
 		for _, key := range filters {
 			var requestData map[string]any
 			// first attempt uses configured key; if that yields empty, we’ll retry with next key
@@ -120,7 +126,7 @@ func (g *gtaSource) Fetch(ctx context.Context) ([]model.Event, error) {
 
 			// Do request with retries
 			var resp *http.Response
-			err = util.Retry(ctx, max(1, g.cfg.MaxRetries), defaultDur(g.cfg.Backoff, 500*time.Millisecond), defaultDur(g.cfg.MaxBackoff, 5*time.Second), func() error {
+			err = util.Retry(ctx, g.Name(), max(1, g.cfg.MaxRetries), defaultDur(g.cfg.Backoff, 500*time.Millisecond), defaultDur(g.cfg.MaxBackoff, 5*time.Second), nil, func() error {
 				req, err := mkReq()
 				if err != nil {
 					return err
@@ -129,22 +135,18 @@ func (g *gtaSource) Fetch(ctx context.Context) ([]model.Event, error) {
 				if err != nil {
 					return err
 				}
-				if r.StatusCode/100 == 4 {
-					b, _ := io.ReadAll(io.LimitReader(r.Body, 4096))
-					r.Body.Close()
-					return fmt.Errorf("gta quota: %s", strings.TrimSpace(string(b)))
-				}
 				if r.StatusCode/100 != 2 {
 					b, _ := io.ReadAll(io.LimitReader(r.Body, 4096))
 					r.Body.Close()
-					return fmt.Errorf("gta %d: %s", r.StatusCode, strings.TrimSpace(string(b)))
+					return &util.HTTPStatusError{StatusCode: r.StatusCode, Body: strings.TrimSpace(string(b))}
 				}
 				resp = r
 				log.Printf("gta: Status Code:  %v", resp.StatusCode)
 				return nil
 			})
 			if err != nil {
-				if strings.HasPrefix(err.Error(), "gta quota:") && len(all) > 0 {
+				var statusErr *util.HTTPStatusError
+				if errors.As(err, &statusErr) && statusErr.StatusCode/100 == 4 && len(all) > 0 {
 					break
 				}
 				return nil, err
@@ -212,12 +214,19 @@ func (g *gtaSource) Fetch(ctx context.Context) ([]model.Event, error) {
 			// DEBUG: show parsed row count
 
 			// Metrics: count rows by filter used
-			metrics.IncCounter("gta_events_total", map[string]string{"filter": usedFilter}, float64(len(flat)))
+			metrics.IncCounter("ingester_source_rows_parsed_total", map[string]string{"source": "gta", "endpoint": endpoint, "filter": key}, float64(len(flat)))
 
 			// ---- map rows -> events ----
 			mappedThisPage := 0
 			for i, m := range flat {
 				id := fmt.Sprint(m["intervention_id"])
+				if g.dedup != nil {
+					dedupKey := g.Name() + "::" + id
+					if g.dedup.Seen(dedupKey) {
+						continue
+					}
+					g.dedup.Mark(dedupKey)
+				}
 				title := fmt.Sprint(m["state_act_title"])
 				url := fmt.Sprint(m["intervention_url"])
 
@@ -282,7 +291,6 @@ func (g *gtaSource) Fetch(ctx context.Context) ([]model.Event, error) {
 
 			offset += len(flat)
 
-			usedFilter = key
 			if len(flat) > 0 {
 				break
 			}
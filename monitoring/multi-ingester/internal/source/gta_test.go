@@ -0,0 +1,128 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"time-value-analyser/multi-ingester/internal/config"
+	"time-value-analyser/multi-ingester/internal/testkit"
+)
+
+// goldenEvent mirrors model.Event minus the Raw field, which echoes the
+// fixture payload verbatim and would make the golden file redundant with
+// the fixture itself.
+type goldenEvent struct {
+	ID        string            `json:"id"`
+	Source    string            `json:"source"`
+	Title     string            `json:"title"`
+	Summary   string            `json:"summary"`
+	URL       string            `json:"url"`
+	Published time.Time         `json:"published"`
+	Lang      string            `json:"lang"`
+	Country   string            `json:"country"`
+	Labels    map[string]string `json:"labels"`
+}
+
+func TestGTASourceFetch(t *testing.T) {
+	rt, err := testkit.LoadFixtures("testdata/vectors/gta")
+	if err != nil {
+		t.Fatalf("load fixtures: %v", err)
+	}
+
+	g := NewGTASource(config.GTAConfig{
+		BaseURL:    "https://fixture.test",
+		Since:      "2024-01-01",
+		DateFilter: "announcement_period",
+	}, nil)
+	g.client.Transport = rt
+	g.now = func() time.Time { return time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC) }
+
+	events, err := g.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	got := make([]goldenEvent, 0, len(events))
+	for _, e := range events {
+		got = append(got, goldenEvent{
+			ID: e.ID, Source: e.Source, Title: e.Title, Summary: e.Summary,
+			URL: e.URL, Published: e.Published, Lang: e.Lang, Country: e.Country,
+			Labels: e.Labels,
+		})
+	}
+
+	raw, err := os.ReadFile("testdata/golden/gta_fetch.json")
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	var want []goldenEvent
+	if err := json.Unmarshal(raw, &want); err != nil {
+		t.Fatalf("parse golden: %v", err)
+	}
+
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("Fetch() mismatch:\n got:  %s\n want: %s", gotJSON, wantJSON)
+	}
+}
+
+func TestPickImplementer(t *testing.T) {
+	cases := []struct {
+		name     string
+		m        map[string]any
+		wantISO  string
+		wantName string
+	}{
+		{
+			name: "implementing_jurisdictions",
+			m: map[string]any{
+				"implementing_jurisdictions": []any{
+					map[string]any{"iso3": "usa", "name": "United States of America"},
+				},
+			},
+			wantISO:  "USA",
+			wantName: "United States of America",
+		},
+		{
+			name:     "implementers string list",
+			m:        map[string]any{"implementers": []any{"deu"}},
+			wantISO:  "DEU",
+			wantName: "",
+		},
+		{
+			name: "implementers object list",
+			m: map[string]any{
+				"implementers": []any{
+					map[string]any{"code": "fra", "name": "France"},
+				},
+			},
+			wantISO:  "FRA",
+			wantName: "France",
+		},
+		{
+			name:     "implementer_iso3",
+			m:        map[string]any{"implementer_iso3": "gbr"},
+			wantISO:  "GBR",
+			wantName: "",
+		},
+		{
+			name:     "no implementer info",
+			m:        map[string]any{},
+			wantISO:  "",
+			wantName: "",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			iso, name := pickImplementer(tc.m)
+			if iso != tc.wantISO || name != tc.wantName {
+				t.Errorf("pickImplementer(%v) = (%q, %q), want (%q, %q)", tc.m, iso, name, tc.wantISO, tc.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package source
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	cron "github.com/robfig/cron/v3"
+
+	"time-value-analyser/multi-ingester/internal/config"
+	"time-value-analyser/multi-ingester/internal/metrics"
+	"time-value-analyser/multi-ingester/internal/model"
+)
+
+// StateFileAware is implemented by sources that persist a cursor file
+// (internal/store). The scheduler uses its path to seed
+// ingester_source_last_success_timestamp_seconds from the file's mtime on
+// startup, so a restart doesn't report a source as stale until its next
+// scheduled fetch completes.
+type StateFileAware interface {
+	StatePath() string
+}
+
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// Batch is one source's fetch result, handed to whatever consumes the
+// Scheduler's output channel (dedup/postprocess/sink fan-out).
+type Batch struct {
+	Source string
+	Events []model.Event
+	Err    error
+}
+
+// scheduledSource pairs a Source with its per-source schedule so each can
+// run on its own goroutine instead of blocking the whole pipeline.
+type scheduledSource struct {
+	src Source
+	cfg config.ScheduleConfig
+
+	mu          sync.RWMutex
+	lastSuccess time.Time
+}
+
+// Scheduler runs each configured Source on its own goroutine with a
+// context.WithTimeout, emitting fetched batches onto a shared bounded
+// channel and recording per-source Prometheus-style metrics.
+type Scheduler struct {
+	sources []*scheduledSource
+	out     chan Batch
+	sem     chan struct{}
+}
+
+// NewScheduler builds a Scheduler for srcs, paired positionally with cfgs.
+// out is the bounded channel fetched batches are pushed to; outBuf sizes it.
+func NewScheduler(srcs []Source, cfgs []config.ScheduleConfig, outBuf int) *Scheduler {
+	if outBuf <= 0 {
+		outBuf = 64
+	}
+	maxConcurrent := 0
+	for _, c := range cfgs {
+		if c.MaxConcurrent > maxConcurrent {
+			maxConcurrent = c.MaxConcurrent
+		}
+	}
+	if maxConcurrent <= 0 {
+		maxConcurrent = 4
+	}
+
+	scheduled := make([]*scheduledSource, len(srcs))
+	for i, s := range srcs {
+		var cfg config.ScheduleConfig
+		if i < len(cfgs) {
+			cfg = cfgs[i]
+		}
+		scheduled[i] = &scheduledSource{src: s, cfg: cfg}
+	}
+
+	return &Scheduler{
+		sources: scheduled,
+		out:     make(chan Batch, outBuf),
+		sem:     make(chan struct{}, maxConcurrent),
+	}
+}
+
+// Out returns the channel fetched batches are delivered on. Run closes it
+// once ctx is done and every source goroutine has exited.
+func (s *Scheduler) Out() <-chan Batch { return s.out }
+
+// Run starts one goroutine per source and blocks until ctx is cancelled and
+// all of them have returned.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, ss := range s.sources {
+		wg.Add(1)
+		go func(ss *scheduledSource) {
+			defer wg.Done()
+			s.runSource(ctx, ss)
+		}(ss)
+	}
+	wg.Wait()
+	close(s.out)
+}
+
+func (s *Scheduler) runSource(ctx context.Context, ss *scheduledSource) {
+	name := ss.src.Name()
+	ss.seedLastSuccessFromStateFile(name)
+
+	if strm, ok := ss.src.(Streamer); ok {
+		s.runStream(ctx, ss, strm)
+		return
+	}
+
+	schedule, err := nextScheduleFn(ss.cfg)
+	if err != nil {
+		log.Printf("scheduler: %s: bad schedule, falling back to 15m interval: %v", name, err)
+		schedule = fixedInterval(15 * time.Minute)
+	}
+
+	onError := ss.cfg.OnError
+	if onError == "" {
+		onError = "skip"
+	}
+	backoff := 0
+	for {
+		wait := schedule()
+		if j := ss.cfg.Jitter; j > 0 {
+			wait += time.Duration(rand.Int63n(int64(j)))
+		}
+		if backoff > 0 {
+			wait += time.Duration(backoff) * time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		select {
+		case s.sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		err := s.fetchOnce(ctx, ss)
+		<-s.sem
+
+		if err != nil {
+			metrics.IncCounter("ingester_source_fetch_total", map[string]string{"source": name, "status": "error"}, 1)
+			switch onError {
+			case "halt":
+				log.Printf("scheduler: %s: halting after error: %v", name, err)
+				return
+			case "backoff":
+				if backoff == 0 {
+					backoff = 1
+				} else if backoff < 300 {
+					backoff *= 2
+				}
+				log.Printf("scheduler: %s: backing off %ds after error: %v", name, backoff, err)
+			default: // skip
+				log.Printf("scheduler: %s: skipping after error: %v", name, err)
+			}
+			continue
+		}
+		backoff = 0
+	}
+}
+
+// runStream drives a Streamer source: it calls Stream repeatedly, emitting
+// each event as its own single-event Batch, and reconnects with doubling
+// backoff whenever Stream returns (a dropped connection, not necessarily a
+// fatal error). It never falls back to the poll loop itself — a Streamer
+// that can't connect at all is expected to fall back to polling Fetch
+// internally (see pollFallback), as the CoinDesk source does; the generic
+// websocket source has nothing to poll and returns the dial error instead.
+//
+// A Streamer may go an arbitrarily long time without producing an event
+// (a quiet feed, or Stream internally polling and finding nothing new via
+// pollFallback) -- unlike fetchOnce, which always pushes a Batch once per
+// tick whether or not Fetch found anything. So runStream also emits an
+// empty marker Batch (Events: nil) on an idleMarker cadence whenever
+// nothing else has been sent, giving every configured source the same
+// "produced one batch per cycle" guarantee -once relies on to terminate.
+func (s *Scheduler) runStream(ctx context.Context, ss *scheduledSource, strm Streamer) {
+	name := ss.src.Name()
+	backoff := 500 * time.Millisecond
+	const maxBackoff = 30 * time.Second
+	idleMarker := ss.cfg.Interval
+	if idleMarker <= 0 {
+		idleMarker = 30 * time.Second
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		events := make(chan model.Event)
+		done := make(chan error, 1)
+		go func() { done <- strm.Stream(ctx, events) }()
+
+		idle := time.NewTimer(idleMarker)
+
+	drain:
+		for {
+			select {
+			case ev, ok := <-events:
+				if !idle.Stop() {
+					<-idle.C
+				}
+				if !ok {
+					break drain
+				}
+				batch := Batch{Source: name, Events: []model.Event{ev}}
+				select {
+				case s.out <- batch:
+				case <-ctx.Done():
+					return
+				}
+				metrics.IncCounter("ingester_source_events_emitted_total", map[string]string{"source": name}, 1)
+				ss.mu.Lock()
+				ss.lastSuccess = time.Now()
+				ss.mu.Unlock()
+				metrics.SetGauge("ingester_source_last_success_timestamp_seconds", map[string]string{"source": name}, float64(ss.lastSuccess.Unix()))
+				backoff = 500 * time.Millisecond // reset once we're successfully delivering
+				idle.Reset(idleMarker)
+			case <-idle.C:
+				select {
+				case s.out <- Batch{Source: name}:
+				case <-ctx.Done():
+					return
+				}
+				idle.Reset(idleMarker)
+			case err := <-done:
+				if !idle.Stop() {
+					<-idle.C
+				}
+				if err != nil && ctx.Err() == nil {
+					metrics.IncCounter("ingester_source_fetch_total", map[string]string{"source": name, "status": "error"}, 1)
+					log.Printf("scheduler: %s: stream ended, reconnecting in %s: %v", name, backoff, err)
+				}
+				break drain
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (s *Scheduler) fetchOnce(ctx context.Context, ss *scheduledSource) error {
+	name := ss.src.Name()
+	timeout := ss.cfg.Timeout
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	events, err := ss.src.Fetch(fetchCtx)
+	metrics.SetGauge("ingester_source_fetch_duration_seconds", map[string]string{"source": name}, time.Since(start).Seconds())
+
+	batch := Batch{Source: name, Events: events, Err: err}
+	select {
+	case s.out <- batch:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if err != nil {
+		return err
+	}
+	metrics.IncCounter("ingester_source_fetch_total", map[string]string{"source": name, "status": "ok"}, 1)
+	metrics.IncCounter("ingester_source_events_emitted_total", map[string]string{"source": name}, float64(len(events)))
+	ss.mu.Lock()
+	ss.lastSuccess = time.Now()
+	ss.mu.Unlock()
+	metrics.SetGauge("ingester_source_last_success_timestamp_seconds", map[string]string{"source": name}, float64(ss.lastSuccess.Unix()))
+	return nil
+}
+
+// seedLastSuccessFromStateFile initializes the last-success gauge from an
+// existing state file's mtime, if ss's source persists one: the file is
+// only (re)written after a successful fetch, so its mtime approximates the
+// last success time across restarts.
+func (ss *scheduledSource) seedLastSuccessFromStateFile(name string) {
+	sfa, ok := ss.src.(StateFileAware)
+	if !ok {
+		return
+	}
+	path := sfa.StatePath()
+	if path == "" {
+		return
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	ss.mu.Lock()
+	ss.lastSuccess = fi.ModTime()
+	ss.mu.Unlock()
+	metrics.SetGauge("ingester_source_last_success_timestamp_seconds", map[string]string{"source": name}, float64(fi.ModTime().Unix()))
+}
+
+// nextScheduleFn returns a function producing the wait duration until the
+// next run, built from either cfg.Cron or cfg.Interval.
+func nextScheduleFn(cfg config.ScheduleConfig) (func() time.Duration, error) {
+	if cfg.Cron != "" {
+		sched, err := cronParser.Parse(cfg.Cron)
+		if err != nil {
+			return nil, err
+		}
+		return func() time.Duration {
+			now := time.Now()
+			return sched.Next(now).Sub(now)
+		}, nil
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	return fixedInterval(interval), nil
+}
+
+func fixedInterval(d time.Duration) func() time.Duration {
+	first := true
+	return func() time.Duration {
+		if first {
+			first = false
+			return 0
+		}
+		return d
+	}
+}
+
+// unhealthyAfter returns whether ss hasn't had a successful fetch within
+// 2*cfg.Interval (or 2*15m if no interval is configured, which includes
+// cron-only schedules).
+func (ss *scheduledSource) unhealthyAfter() bool {
+	ss.mu.RLock()
+	last := ss.lastSuccess
+	ss.mu.RUnlock()
+	if last.IsZero() {
+		return false // hasn't had a chance to run yet
+	}
+	interval := ss.cfg.Interval
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	return time.Since(last) > 2*interval
+}
+
+// HealthHandler serves /healthz (always 200 once the process is up) and
+// /readyz (503 once any source's last_success age exceeds 2x its interval).
+func (s *Scheduler) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		for _, ss := range s.sources {
+			if ss.unhealthyAfter() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte("stale: " + ss.src.Name()))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	return mux
+}
@@ -6,6 +6,7 @@ import (
 
 	"time-value-analyser/multi-ingester/internal/config"
 	"time-value-analyser/multi-ingester/internal/model"
+	"time-value-analyser/multi-ingester/internal/store"
 )
 
 type Source interface {
@@ -13,12 +14,17 @@ type Source interface {
 	Fetch(ctx context.Context) ([]model.Event, error)
 }
 
-func NewFromConfig(c config.SourceConfig) (Source, error) {
+// NewFromConfig builds the configured Source. dedup may be nil; sources that
+// accept it consult it before appending a row to their result slice, so
+// pagination overlaps and re-fetched windows don't produce duplicate events.
+func NewFromConfig(c config.SourceConfig, dedup store.Dedup) (Source, error) {
 	switch c.Type {
 	case "gta":
-		return NewGTASource(c.GTA), nil
+		return NewGTASource(c.GTA, dedup), nil
 	case "coindesk":
 		return NewCoinDeskSource(c.CoinDesk), nil
+	case "websocket":
+		return NewWebSocketSource(c.WebSocket), nil
 	default:
 		return nil, fmt.Errorf("unknown source type: %s", c.Type)
 	}
@@ -0,0 +1,96 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"time-value-analyser/multi-ingester/internal/model"
+)
+
+// Streamer is implemented by sources that can push events as they happen
+// instead of being polled. Stream blocks, writing events to out until ctx
+// is cancelled or it gives up; the Scheduler treats a return as "retry
+// Stream from scratch" after a backoff. The Scheduler never polls Fetch for
+// a Streamer itself; falling back to polling is up to the Streamer (see
+// pollFallback).
+type Streamer interface {
+	Stream(ctx context.Context, out chan<- model.Event) error
+}
+
+// dialWithRetry attempts to connect to url up to attempts times with
+// exponential backoff (the delay doubles each attempt, capped at maxBackoff;
+// unlike util.Retry it adds no jitter). It
+// returns an error once exhausted so the caller can fall back to polling
+// instead of blocking on a feed that may not support websockets at all.
+func dialWithRetry(ctx context.Context, name, url string, attempts int, initial, maxBackoff time.Duration) (*websocket.Conn, error) {
+	if attempts <= 0 {
+		attempts = 5
+	}
+	d := initial
+	if d <= 0 {
+		d = 500 * time.Millisecond
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(d):
+			}
+			d *= 2
+			if d > maxBackoff {
+				d = maxBackoff
+			}
+		}
+		conn, resp, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if resp != nil {
+			log.Printf("stream: %s: dial %s failed (http %d), attempt %d/%d: %v", name, url, resp.StatusCode, i+1, attempts, err)
+		} else {
+			log.Printf("stream: %s: dial %s failed, attempt %d/%d: %v", name, url, i+1, attempts, err)
+		}
+	}
+	return nil, fmt.Errorf("stream: %s: exhausted %d dial attempts: %w", name, attempts, lastErr)
+}
+
+// pollFallback runs Fetch on a fixed interval and writes its events to out,
+// used when a Streamer's underlying transport never manages to connect
+// (e.g. the deployment's feed doesn't support websockets).
+func pollFallback(ctx context.Context, name string, interval time.Duration, fetch func(context.Context) ([]model.Event, error), out chan<- model.Event) error {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	log.Printf("stream: %s: falling back to polling every %s", name, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			events, err := fetch(ctx)
+			if err != nil {
+				log.Printf("stream: %s: poll fallback fetch: %v", name, err)
+				continue
+			}
+			for _, e := range events {
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"time-value-analyser/multi-ingester/internal/config"
+	"time-value-analyser/multi-ingester/internal/model"
+	"time-value-analyser/multi-ingester/internal/store"
+)
+
+// wsSource is a push-only Source for feeds that need nothing beyond "open
+// a websocket, read JSON objects, map obvious fields". Sources with
+// source-specific pagination/auth (CoinDesk) implement Streamer directly
+// instead.
+type wsSource struct {
+	cfg config.WebSocketConfig
+}
+
+func NewWebSocketSource(cfg config.WebSocketConfig) *wsSource {
+	return &wsSource{cfg: cfg}
+}
+
+func (w *wsSource) Name() string { return "websocket" }
+
+// StatePath satisfies StateFileAware so the scheduler can seed
+// ingester_source_last_success_timestamp_seconds from this file's mtime.
+func (w *wsSource) StatePath() string { return w.cfg.StatePath }
+
+// Fetch has nothing to poll; wsSource only produces events via Stream. It
+// exists solely so wsSource satisfies Source.
+func (w *wsSource) Fetch(ctx context.Context) ([]model.Event, error) {
+	return nil, nil
+}
+
+// Stream satisfies Streamer: connect to cfg.URL, map each inbound JSON
+// object to a model.Event with best-effort field names, dedupe against a
+// bounded LRU, and checkpoint LastPublished periodically. It does not use
+// pollFallback (Fetch has nothing to poll): once the dial attempts are
+// exhausted it returns the error and the scheduler retries Stream with
+// backoff, so a feed with no websocket support never produces events.
+func (w *wsSource) Stream(ctx context.Context, out chan<- model.Event) error {
+	if w.cfg.URL == "" {
+		return fmt.Errorf("websocket: url not configured")
+	}
+
+	dedup, err := store.NewDedupFromConfig(config.DedupConfig{
+		Backend: "memory",
+		MaxKeys: w.cfg.DedupWindow,
+		TTL:     24 * time.Hour,
+	})
+	if err != nil {
+		return fmt.Errorf("websocket: dedup: %w", err)
+	}
+	defer dedup.Close()
+
+	conn, err := dialWithRetry(ctx, w.Name(), w.cfg.URL, max(1, w.cfg.MaxRetries), defaultDur(w.cfg.Backoff, 500*time.Millisecond), defaultDur(w.cfg.MaxBackoff, 30*time.Second))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	persistEvery := w.cfg.PersistEvery
+	if persistEvery <= 0 {
+		persistEvery = 50
+	}
+	persistInterval := defaultDur(w.cfg.PersistInterval, 10*time.Second)
+
+	var latest time.Time
+	sinceEvents := 0
+	lastPersist := time.Now()
+	checkpoint := func() {
+		if w.cfg.StatePath == "" || latest.IsZero() {
+			return
+		}
+		if err := store.SaveNewsState(w.cfg.StatePath, store.NewsState{LastPublished: latest.Format(time.RFC3339)}); err != nil {
+			log.Printf("websocket: save state: %v", err)
+		}
+		sinceEvents = 0
+		lastPersist = time.Now()
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			checkpoint()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("websocket: read: %w", err)
+		}
+
+		var m map[string]any
+		if err := json.Unmarshal(raw, &m); err != nil {
+			continue
+		}
+		ev := w.mapEvent(m)
+		if ev.ID != "" {
+			key := w.Name() + "::" + ev.ID
+			if dedup.Seen(key) {
+				continue
+			}
+			dedup.Mark(key)
+		}
+		if ev.Published.After(latest) {
+			latest = ev.Published
+		}
+
+		select {
+		case out <- ev:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		sinceEvents++
+		if sinceEvents >= persistEvery || time.Since(lastPersist) >= persistInterval {
+			checkpoint()
+		}
+	}
+}
+
+func (w *wsSource) mapEvent(m map[string]any) model.Event {
+	id := pickStr(m, "id", "uuid", "_id")
+	title := pickStr(m, "title", "headline", "name")
+	urlstr := pickStr(m, "url", "link", "permalink")
+	summary := pickStr(m, "summary", "description", "body")
+
+	ts := time.Now().UTC()
+	if s := pickStr(m, "published_at", "timestamp", "time"); s != "" {
+		if t, err := parseTimeFlexible(s); err == nil {
+			ts = t
+		}
+	}
+
+	return model.Event{
+		ID:        id,
+		Source:    w.Name(),
+		Title:     title,
+		Summary:   summary,
+		URL:       urlstr,
+		Published: ts,
+		Raw:       m,
+		Labels:    map[string]string{},
+	}
+}
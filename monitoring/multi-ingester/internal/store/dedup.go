@@ -2,12 +2,41 @@ package store
 
 import (
 	"container/list"
+	"fmt"
 	"sync"
 	"time"
+
+	"time-value-analyser/multi-ingester/internal/config"
 )
 
-// Dedup is a tiny TTL-bound LRU for seen IDs.
-type Dedup struct {
+// Dedup tracks which keys have already been pushed, so a restart doesn't
+// re-emit every event since the last source cursor. Both the GTA source
+// (before appending to its result slice) and the Loki sink (before enqueue)
+// consult it.
+type Dedup interface {
+	Seen(key string) bool
+	Mark(key string)
+	Close() error
+}
+
+// NewDedupFromConfig builds the configured Dedup backend: "memory" (default),
+// "bolt", or "badger+bloom".
+func NewDedupFromConfig(cfg config.DedupConfig) (Dedup, error) {
+	switch cfg.Backend {
+	case "", "memory":
+		return newMemoryDedup(cfg.MaxKeys, cfg.TTL), nil
+	case "bolt":
+		return newBoltDedup(cfg)
+	case "badger+bloom":
+		return newBadgerBloomDedup(cfg)
+	default:
+		return nil, fmt.Errorf("store: unknown dedup backend %q", cfg.Backend)
+	}
+}
+
+// memoryDedup is a tiny TTL-bound LRU for seen IDs. It loses state on
+// process restart; use "bolt" or "badger+bloom" when that matters.
+type memoryDedup struct {
 	mu    sync.Mutex
 	cap   int
 	ttl   time.Duration
@@ -20,17 +49,17 @@ type entry struct {
 	exp time.Time
 }
 
-func NewDedup(maxKeys int, ttl time.Duration) *Dedup {
+func newMemoryDedup(maxKeys int, ttl time.Duration) *memoryDedup {
 	if maxKeys <= 0 {
 		maxKeys = 10000
 	}
 	if ttl <= 0 {
 		ttl = 24 * time.Hour
 	}
-	return &Dedup{cap: maxKeys, ttl: ttl, ll: list.New(), items: make(map[string]*list.Element, maxKeys)}
+	return &memoryDedup{cap: maxKeys, ttl: ttl, ll: list.New(), items: make(map[string]*list.Element, maxKeys)}
 }
 
-func (d *Dedup) Seen(key string) bool {
+func (d *memoryDedup) Seen(key string) bool {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	if el, ok := d.items[key]; ok {
@@ -47,7 +76,7 @@ func (d *Dedup) Seen(key string) bool {
 	return false
 }
 
-func (d *Dedup) Mark(key string) {
+func (d *memoryDedup) Mark(key string) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	if el, ok := d.items[key]; ok {
@@ -82,3 +111,5 @@ func (d *Dedup) Mark(key string) {
 		delete(d.items, t.Value.(entry).key)
 	}
 }
+
+func (d *memoryDedup) Close() error { return nil }
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bits-and-blooms/bloom/v3"
+	badger "github.com/dgraph-io/badger/v4"
+
+	"time-value-analyser/multi-ingester/internal/config"
+)
+
+const bloomFile = "dedup.bloom"
+
+// badgerBloomDedup fronts a Badger-backed persistent store with a Bloom
+// filter fast-path: the common "unseen" case never touches disk. The filter
+// is saved on Close and reloaded on startup, so it doesn't need rebuilding
+// (and its false-positive rate) from scratch every restart.
+type badgerBloomDedup struct {
+	db        *badger.DB
+	filter    *bloom.BloomFilter
+	filterDir string
+	ttl       time.Duration
+	stopCh    chan struct{}
+}
+
+func newBadgerBloomDedup(cfg config.DedupConfig) (*badgerBloomDedup, error) {
+	dir := cfg.Path
+	if dir == "" {
+		dir = "dedup-badger"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("store: create badger dir %s: %w", dir, err)
+	}
+
+	opts := badger.DefaultOptions(dir).WithLogger(nil)
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("store: open badger dedup at %s: %w", dir, err)
+	}
+
+	filter, err := loadBloom(filepath.Join(dir, bloomFile), cfg.BloomExpectedItems, cfg.BloomFPRate)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	d := &badgerBloomDedup{db: db, filter: filter, filterDir: dir, ttl: ttl, stopCh: make(chan struct{})}
+	go d.gcLoop(cfg.SweepInterval)
+	return d, nil
+}
+
+func loadBloom(path string, expectedItems uint, fpRate float64) (*bloom.BloomFilter, error) {
+	if expectedItems == 0 {
+		expectedItems = 1_000_000
+	}
+	if fpRate <= 0 {
+		fpRate = 0.01
+	}
+	if f, err := os.Open(path); err == nil {
+		defer f.Close()
+		filter := &bloom.BloomFilter{}
+		if _, err := filter.ReadFrom(f); err == nil {
+			return filter, nil
+		}
+	}
+	return bloom.NewWithEstimates(expectedItems, fpRate), nil
+}
+
+func (d *badgerBloomDedup) Seen(key string) bool {
+	// Fast path: the filter can only produce false positives, never false
+	// negatives, so "definitely not seen" short-circuits the disk lookup.
+	if !d.filter.TestString(key) {
+		return false
+	}
+	seen := false
+	err := d.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// badger evicts the entry itself once its TTL elapses, so any hit here is live.
+		_ = item
+		seen = true
+		return nil
+	})
+	if err != nil {
+		return false
+	}
+	return seen
+}
+
+func (d *badgerBloomDedup) Mark(key string) {
+	d.filter.AddString(key)
+	_ = d.db.Update(func(txn *badger.Txn) error {
+		e := badger.NewEntry([]byte(key), []byte{1}).WithTTL(d.ttl)
+		return txn.SetEntry(e)
+	})
+}
+
+func (d *badgerBloomDedup) Close() error {
+	close(d.stopCh)
+	if err := d.saveBloom(); err != nil {
+		d.db.Close()
+		return err
+	}
+	return d.db.Close()
+}
+
+func (d *badgerBloomDedup) saveBloom() error {
+	f, err := os.Create(filepath.Join(d.filterDir, bloomFile))
+	if err != nil {
+		return fmt.Errorf("store: save bloom filter: %w", err)
+	}
+	defer f.Close()
+	_, err = d.filter.WriteTo(f)
+	return err
+}
+
+func (d *badgerBloomDedup) gcLoop(interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		case <-t.C:
+			// Badger recommends looping value-log GC until it returns an error (no more to reclaim).
+			for d.db.RunValueLogGC(0.5) == nil {
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"time-value-analyser/multi-ingester/internal/config"
+)
+
+var dedupBucket = []byte("dedup")
+
+// boltDedup persists {key -> expiresAt} in a BoltDB file so a restart
+// doesn't forget what's already been pushed. A background goroutine sweeps
+// expired keys on cfg.SweepInterval.
+type boltDedup struct {
+	db     *bolt.DB
+	ttl    time.Duration
+	stopCh chan struct{}
+}
+
+func newBoltDedup(cfg config.DedupConfig) (*boltDedup, error) {
+	path := cfg.Path
+	if path == "" {
+		path = "dedup.bolt"
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("store: open bolt dedup at %s: %w", path, err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(dedupBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("store: init bolt dedup bucket: %w", err)
+	}
+
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	d := &boltDedup{db: db, ttl: ttl, stopCh: make(chan struct{})}
+	go d.sweepLoop(cfg.SweepInterval)
+	return d, nil
+}
+
+func (d *boltDedup) Seen(key string) bool {
+	seen := false
+	_ = d.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(dedupBucket).Get([]byte(key))
+		if v == nil {
+			return nil
+		}
+		if expiresAt(v).After(time.Now()) {
+			seen = true
+		}
+		return nil
+	})
+	return seen
+}
+
+func (d *boltDedup) Mark(key string) {
+	_ = d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(dedupBucket).Put([]byte(key), encodeExpiry(time.Now().Add(d.ttl)))
+	})
+}
+
+func (d *boltDedup) Close() error {
+	close(d.stopCh)
+	return d.db.Close()
+}
+
+func (d *boltDedup) sweepLoop(interval time.Duration) {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		case <-t.C:
+			d.sweepExpired()
+		}
+	}
+}
+
+func (d *boltDedup) sweepExpired() {
+	now := time.Now()
+	_ = d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(dedupBucket)
+		c := b.Cursor()
+		var stale [][]byte
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if expiresAt(v).Before(now) {
+				stale = append(stale, append([]byte(nil), k...))
+			}
+		}
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func encodeExpiry(t time.Time) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(t.UnixNano()))
+	return buf
+}
+
+func expiresAt(v []byte) time.Time {
+	if len(v) != 8 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(v)))
+}
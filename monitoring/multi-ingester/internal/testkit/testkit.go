@@ -0,0 +1,155 @@
+// Package testkit records and replays HTTP interactions against versioned
+// JSON fixtures, so sources can be regression-tested without hitting real
+// upstream APIs. This turns ad-hoc log.Printf debugging of shape drift
+// (GTA's results/count wrapper vs a top-level array, CoinGecko's simple
+// price map, ...) into a real suite.
+package testkit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Fixture is one recorded request/response pair, stored as
+// testdata/vectors/{source}/{name}.json.
+type Fixture struct {
+	Name     string    `json:"name"`
+	Request  ReqRecord `json:"request"`
+	Response RspRecord `json:"response"`
+}
+
+type ReqRecord struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body"`
+}
+
+type RspRecord struct {
+	Status int    `json:"status"`
+	Body   string `json:"body"`
+}
+
+// RecordingTransport wraps an http.RoundTripper and writes a Fixture to Dir
+// for every request it proxies, named "<Prefix><n>.json".
+type RecordingTransport struct {
+	Next   http.RoundTripper
+	Dir    string
+	Prefix string
+
+	seq int
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	var reqBody []byte
+	if req.Body != nil {
+		reqBody, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rspBody, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(rspBody))
+
+	t.seq++
+	fx := Fixture{
+		Name: fmt.Sprintf("%s%d", t.Prefix, t.seq),
+		Request: ReqRecord{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Body:   string(reqBody),
+		},
+		Response: RspRecord{
+			Status: resp.StatusCode,
+			Body:   string(rspBody),
+		},
+	}
+	if err := writeFixture(t.Dir, fx); err != nil {
+		return resp, fmt.Errorf("testkit: record fixture: %w", err)
+	}
+	return resp, nil
+}
+
+func writeFixture(dir string, fx Fixture) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, fx.Name+".json"), b, 0644)
+}
+
+// ReplayTransport serves canned responses for requests matching a recorded
+// fixture by method + URL + request-body hash.
+type ReplayTransport struct {
+	byKey map[string]Fixture
+}
+
+// LoadFixtures reads every *.json file in dir into a ReplayTransport.
+func LoadFixtures(dir string) (*ReplayTransport, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("testkit: read fixtures dir %s: %w", dir, err)
+	}
+	rt := &ReplayTransport{byKey: make(map[string]Fixture)}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var fx Fixture
+		if err := json.Unmarshal(b, &fx); err != nil {
+			return nil, fmt.Errorf("testkit: parse fixture %s: %w", e.Name(), err)
+		}
+		rt.byKey[matchKey(fx.Request.Method, fx.Request.URL, fx.Request.Body)] = fx
+	}
+	return rt, nil
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	key := matchKey(req.Method, req.URL.String(), string(body))
+	fx, ok := t.byKey[key]
+	if !ok {
+		return nil, fmt.Errorf("testkit: no fixture recorded for %s %s", req.Method, req.URL.String())
+	}
+	resp := &http.Response{
+		StatusCode: fx.Response.Status,
+		Status:     http.StatusText(fx.Response.Status),
+		Header:     make(http.Header),
+		Body:       io.NopCloser(bytes.NewReader([]byte(fx.Response.Body))),
+		Request:    req,
+	}
+	return resp, nil
+}
+
+func matchKey(method, url, body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return method + " " + url + " " + hex.EncodeToString(sum[:])
+}
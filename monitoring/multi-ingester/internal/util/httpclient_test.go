@@ -0,0 +1,120 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetry_PermanentErrorStopsImmediately(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), "t", 5, time.Millisecond, 10*time.Millisecond, rand.New(rand.NewSource(1)), func() error {
+		calls++
+		return &HTTPStatusError{StatusCode: http.StatusBadRequest}
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Fatalf("permanent error should not be retried, got %d calls", calls)
+	}
+}
+
+func TestRetry_RetryableErrorExhaustsAttempts(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), "t", 3, time.Millisecond, 10*time.Millisecond, rand.New(rand.NewSource(1)), func() error {
+		calls++
+		return &HTTPStatusError{StatusCode: http.StatusServiceUnavailable}
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRetry_SucceedsAfterTransientFailure(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), "t", 3, time.Millisecond, 10*time.Millisecond, rand.New(rand.NewSource(1)), func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("connection reset")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 attempts, got %d", calls)
+	}
+}
+
+func TestHTTPStatusError_Retryable(t *testing.T) {
+	cases := []struct {
+		status int
+		want   bool
+	}{
+		{http.StatusBadRequest, false},
+		{http.StatusUnauthorized, false},
+		{http.StatusNotFound, false},
+		{http.StatusRequestTimeout, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+		{http.StatusBadGateway, true},
+	}
+	for _, c := range cases {
+		e := &HTTPStatusError{StatusCode: c.status}
+		if got := e.Retryable(); got != c.want {
+			t.Errorf("status %d: Retryable() = %v, want %v", c.status, got, c.want)
+		}
+	}
+}
+
+// fixedJitter always reports the top of whatever range it's asked for, so
+// nextDelay's output becomes a deterministic function of (prev, initial,
+// max) instead of depending on a real PRNG draw.
+type fixedJitter struct{}
+
+func (fixedJitter) Int63n(n int64) int64 { return n - 1 }
+
+func TestRetry_JitterAppliesFromFirstAttempt(t *testing.T) {
+	initial, max := 2*time.Millisecond, 50*time.Millisecond
+	wantFirstDelay := nextDelay(fixedJitter{}, initial, initial, max)
+	if wantFirstDelay <= initial {
+		t.Fatalf("test setup: fixedJitter should force a delay above initial, got %v", wantFirstDelay)
+	}
+
+	calls := 0
+	start := time.Now()
+	err := Retry(context.Background(), "t", 2, initial, max, fixedJitter{}, func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("connection reset")
+		}
+		return nil
+	})
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed < wantFirstDelay {
+		t.Fatalf("first retry slept %v, want at least the jittered delay %v (jitter must apply before the first sleep, not after it)", elapsed, wantFirstDelay)
+	}
+}
+
+func TestNextDelay_BoundedByMax(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	d := time.Millisecond
+	initial, max := time.Millisecond, 50*time.Millisecond
+	for i := 0; i < 100; i++ {
+		d = nextDelay(rnd, d, initial, max)
+		if d < initial || d > max {
+			t.Fatalf("delay %v out of bounds [%v, %v]", d, initial, max)
+		}
+	}
+}
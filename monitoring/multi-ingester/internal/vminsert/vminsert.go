@@ -0,0 +1,144 @@
+// Package vminsert is a small client for writing samples into
+// VictoriaMetrics over the remote_write/text exposition format used by the
+// live sink (see internal/sink/victoria.go). btc-wallet-exporter's backfill
+// command keeps its own copy of this client for the JSON-lines import
+// format it needs instead -- the two binaries are separate Go modules, so
+// nothing is actually shared at build time.
+package vminsert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// Sample is one labeled data point bound for VictoriaMetrics.
+type Sample struct {
+	Name      string // metric name, e.g. "btc_wallet_balance_sats"
+	Labels    map[string]string
+	Value     float64
+	Timestamp time.Time
+}
+
+// Client POSTs samples to a VictoriaMetrics (or Prometheus-remote_write
+// compatible) endpoint. It holds no retry policy of its own; callers that
+// need retries wrap these methods (see multi-ingester's util.Retry).
+type Client struct {
+	URL       string
+	UserAgent string
+	HTTP      *http.Client
+}
+
+// NewClient builds a Client. httpClient may be nil, in which case
+// http.DefaultClient is used.
+func NewClient(url string, userAgent string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{URL: url, UserAgent: userAgent, HTTP: httpClient}
+}
+
+// PushRemoteWrite snappy-compresses samples as a Prometheus remote_write
+// WriteRequest and POSTs it to /api/v1/write.
+func (c *Client) PushRemoteWrite(ctx context.Context, samples []Sample) error {
+	wr := &prompb.WriteRequest{Timeseries: make([]prompb.TimeSeries, 0, len(samples))}
+	for _, s := range samples {
+		wr.Timeseries = append(wr.Timeseries, prompb.TimeSeries{
+			Labels:  sortedLabels(s.Name, s.Labels),
+			Samples: []prompb.Sample{{Value: s.Value, Timestamp: s.Timestamp.UnixMilli()}},
+		})
+	}
+	raw, err := wr.Marshal()
+	if err != nil {
+		return fmt.Errorf("vminsert: marshal remote_write: %w", err)
+	}
+	body := snappy.Encode(nil, raw)
+	return c.post(ctx, "/api/v1/write", "application/x-protobuf", body, map[string]string{
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	})
+}
+
+// PushTextExposition POSTs pre-rendered Prometheus exposition-format lines
+// to /api/v1/import/prometheus.
+func (c *Client) PushTextExposition(ctx context.Context, body []byte) error {
+	return c.post(ctx, "/api/v1/import/prometheus", "text/plain", body, nil)
+}
+
+// jsonLine is one row of VictoriaMetrics's JSON-lines import format:
+// https://docs.victoriametrics.com/#how-to-import-data-in-json-line-format
+type jsonLine struct {
+	Metric     map[string]string `json:"metric"`
+	Values     []float64         `json:"values"`
+	Timestamps []int64           `json:"timestamps"`
+}
+
+// PushJSONLines encodes samples as newline-delimited JSON and POSTs them to
+// /api/v1/import, VictoriaMetrics's bulk-import format for samples carrying
+// arbitrary (including historical) timestamps.
+func (c *Client) PushJSONLines(ctx context.Context, samples []Sample) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, s := range samples {
+		metric := make(map[string]string, len(s.Labels)+1)
+		metric["__name__"] = s.Name
+		for k, v := range s.Labels {
+			metric[k] = v
+		}
+		if err := enc.Encode(jsonLine{
+			Metric:     metric,
+			Values:     []float64{s.Value},
+			Timestamps: []int64{s.Timestamp.UnixMilli()},
+		}); err != nil {
+			return fmt.Errorf("vminsert: encode json line: %w", err)
+		}
+	}
+	return c.post(ctx, "/api/v1/import", "application/stream+json", buf.Bytes(), nil)
+}
+
+func (c *Client) post(ctx context.Context, path, contentType string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5 {
+		return fmt.Errorf("vminsert: push to %s failed: %s (retryable)", path, resp.Status)
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("vminsert: push to %s failed: %s", path, resp.Status)
+	}
+	return nil
+}
+
+func sortedLabels(name string, lbls map[string]string) []prompb.Label {
+	names := make([]string, 0, len(lbls))
+	for k := range lbls {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	out := make([]prompb.Label, 0, len(names)+1)
+	out = append(out, prompb.Label{Name: "__name__", Value: name})
+	for _, k := range names {
+		out = append(out, prompb.Label{Name: k, Value: lbls[k]})
+	}
+	return out
+}